@@ -0,0 +1,796 @@
+package qrcode
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"math"
+	"sort"
+)
+
+// Decode reads an image (any format registered with the image package, e.g.
+// via blank _ "image/png" imports) from r, locates a QR code in it, and
+// returns its decoded content.
+func Decode(r io.Reader) (string, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return "", fmt.Errorf("qrcode: decoding image: %w", err)
+	}
+	return DecodeImage(img)
+}
+
+// DecodeImage locates and decodes a single QR code within img. It handles
+// reasonably clean, nearly axis-aligned scans: it binarizes the image,
+// locates the three finder patterns, estimates the symbol's version and
+// corner geometry, samples each module through a perspective transform, then
+// reverses masking, block interleaving, and Reed-Solomon coding to recover
+// the original content.
+func DecodeImage(img image.Image) (string, error) {
+	bitmap, w, h := binarize(img)
+
+	tl, tr, bl, moduleSize, err := findFinderPatterns(bitmap, w, h)
+	if err != nil {
+		return "", err
+	}
+	if moduleSize <= 0 {
+		return "", errors.New("qrcode: could not estimate module size")
+	}
+
+	size, version, err := estimateVersion(tl, tr, moduleSize)
+	if err != nil {
+		return "", err
+	}
+
+	br := estimateFourthCorner(tl, tr, bl)
+	brOffset := moduleSize * 3.5
+	if locs := alignmentPatternPositions[version]; len(locs) > 0 {
+		lastLoc := locs[len(locs)-1]
+		seed := estimateAlignmentCenter(tl, tr, bl, moduleSize, lastLoc)
+		if refined, ok := locateAlignmentPattern(bitmap, w, h, seed, moduleSize); ok {
+			br = refined
+			// Unlike tl/tr/bl, br is now an alignment-pattern center, which
+			// sits a version-dependent distance from the symbol edge rather
+			// than the finder patterns' fixed 3.5 modules.
+			brOffset = moduleSize * (float64(size) - 0.5 - float64(lastLoc))
+		}
+	}
+
+	corners := computeOuterCorners(tl, tr, bl, br, moduleSize, brOffset)
+	transform := quadFromSquare(corners[0], corners[1], corners[2], corners[3])
+
+	modules, isFunction := sampleSymbol(bitmap, w, h, transform, size, version)
+
+	if version >= 7 {
+		if v2, err := readVersionInfo(modules, size); err == nil && v2 != version {
+			version = v2
+			size = 21 + 4*(version-1)
+			modules, isFunction = sampleSymbol(bitmap, w, h, transform, size, version)
+		}
+	}
+
+	level, mask, err := readFormatInfo(modules, size)
+	if err != nil {
+		return "", err
+	}
+
+	unmasked := applyMask(modules, isFunction, mask)
+
+	bits := extractCodewordBits(unmasked, isFunction, size)
+	codewords := bitsToBytes(bits)
+
+	vInfo := versionTable[version][level]
+	dataTotal := vInfo.TotalCodewords - vInfo.ECCodewords*vInfo.Blocks
+	blocks := deinterleaveBlocks(codewords, vInfo.Blocks, vInfo.ECCodewords, dataTotal)
+
+	var dataCodewords []int
+	for _, blk := range blocks {
+		full := append(append([]int{}, blk.Data...), blk.EC...)
+		corrected, err := CorrectErrors(full, vInfo.ECCodewords)
+		if err != nil {
+			return "", fmt.Errorf("qrcode: %w", err)
+		}
+		dataCodewords = append(dataCodewords, corrected...)
+	}
+
+	dataBits := make([]bool, 0, len(dataCodewords)*8)
+	for _, cw := range dataCodewords {
+		for i := 7; i >= 0; i-- {
+			dataBits = append(dataBits, (cw>>i)&1 == 1)
+		}
+	}
+
+	return decodeSegments(dataBits, version)
+}
+
+// point is a location in image pixel space.
+type point struct{ X, Y float64 }
+
+func (p point) sub(q point) point { return point{p.X - q.X, p.Y - q.Y} }
+func (p point) add(q point) point { return point{p.X + q.X, p.Y + q.Y} }
+func (p point) dist(q point) float64 {
+	return math.Hypot(p.X-q.X, p.Y-q.Y)
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// binarize converts img to a dark/light bitmap using a per-tile Otsu
+// threshold, which tolerates moderate lighting gradients across the image
+// better than a single global threshold.
+func binarize(img image.Image) ([][]bool, int, int) {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+
+	gray := make([][]uint8, h)
+	for y := 0; y < h; y++ {
+		gray[y] = make([]uint8, w)
+		for x := 0; x < w; x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			lum := (299*r + 587*g + 114*b) / 1000
+			gray[y][x] = uint8(lum >> 8)
+		}
+	}
+
+	const tile = 8
+	bitmap := make([][]bool, h)
+	for i := range bitmap {
+		bitmap[i] = make([]bool, w)
+	}
+
+	for ty := 0; ty < h; ty += tile {
+		for tx := 0; tx < w; tx += tile {
+			y1 := minInt(ty+tile, h)
+			x1 := minInt(tx+tile, w)
+			threshold := otsuThreshold(gray, tx, ty, x1, y1)
+			for y := ty; y < y1; y++ {
+				for x := tx; x < x1; x++ {
+					bitmap[y][x] = gray[y][x] <= threshold
+				}
+			}
+		}
+	}
+	return bitmap, w, h
+}
+
+// otsuThreshold computes Otsu's between-class-variance-maximizing threshold
+// over the gray[y0:y1][x0:x1] tile.
+func otsuThreshold(gray [][]uint8, x0, y0, x1, y1 int) uint8 {
+	var hist [256]int
+	total := 0
+	for y := y0; y < y1; y++ {
+		for x := x0; x < x1; x++ {
+			hist[gray[y][x]]++
+			total++
+		}
+	}
+	if total == 0 {
+		return 128
+	}
+
+	sumAll := 0
+	for i, c := range hist {
+		sumAll += i * c
+	}
+
+	sumB, wB := 0, 0
+	maxVar := -1.0
+	threshold := 128
+	for t := 0; t < 256; t++ {
+		wB += hist[t]
+		if wB == 0 {
+			continue
+		}
+		wF := total - wB
+		if wF == 0 {
+			break
+		}
+		sumB += t * hist[t]
+		mB := float64(sumB) / float64(wB)
+		mF := float64(sumAll-sumB) / float64(wF)
+		diff := mB - mF
+		between := float64(wB) * float64(wF) * diff * diff
+		if between > maxVar {
+			maxVar = between
+			threshold = t
+		}
+	}
+	return uint8(threshold)
+}
+
+// finderHit is one scanline's detection of a candidate finder-pattern
+// center, along with the per-module pixel size implied by that scanline's
+// run lengths (total run span / 7 modules).
+type finderHit struct {
+	pt   point
+	unit float64
+}
+
+// finderCluster accumulates candidate finder-pattern hits from multiple
+// scanlines so nearby hits can be averaged into one center and module size.
+type finderCluster struct {
+	sumX, sumY, sumUnit float64
+	votes               int
+}
+
+func (c *finderCluster) center() point {
+	return point{c.sumX / float64(c.votes), c.sumY / float64(c.votes)}
+}
+
+func (c *finderCluster) moduleSize() float64 {
+	return c.sumUnit / float64(c.votes)
+}
+
+// ratiosMatch reports whether five consecutive run lengths approximate the
+// finder pattern's 1:1:3:1:1 module ratio, within 50% per-run tolerance.
+func ratiosMatch(runs [5]int) bool {
+	total := 0
+	for _, r := range runs {
+		total += r
+	}
+	if total < 7 {
+		return false
+	}
+	unit := float64(total) / 7.0
+	expected := [5]float64{unit, unit, unit * 3, unit, unit}
+	for i, r := range runs {
+		if math.Abs(float64(r)-expected[i]) > expected[i]*0.5+1 {
+			return false
+		}
+	}
+	return true
+}
+
+// scanLineForFinders walks one row (or, transposed, one column) of the
+// bitmap and records the center (and implied module size) of every
+// dark/light run quintuple matching the finder pattern ratio.
+func scanLineForFinders(get func(i int) bool, length int, coordAt func(center float64) point, out *[]finderHit) {
+	var runs [5]int
+	count := 0
+	runStart := 0
+	color := get(0)
+	runLen := 1
+
+	flush := func() {
+		if count < 5 {
+			return
+		}
+		if color && ratiosMatch(runs) {
+			// runs[4] (dark) is the run that just closed at runStart; walk
+			// back past it and runs[3] (light) to the middle dark run
+			// (runs[2]) and take its midpoint as the candidate center.
+			center := runStart - runs[4] - runs[3] - runs[2]/2
+			total := runs[0] + runs[1] + runs[2] + runs[3] + runs[4]
+			*out = append(*out, finderHit{
+				pt:   coordAt(float64(center)),
+				unit: float64(total) / 7.0,
+			})
+		}
+	}
+
+	for i := 1; i < length; i++ {
+		c := get(i)
+		if c == color {
+			runLen++
+			continue
+		}
+		// Run ended; shift the history window.
+		runs[0], runs[1], runs[2], runs[3], runs[4] = runs[1], runs[2], runs[3], runs[4], runLen
+		count++
+		runStart = i
+		flush()
+		color = c
+		runLen = 1
+	}
+	runs[0], runs[1], runs[2], runs[3], runs[4] = runs[1], runs[2], runs[3], runs[4], runLen
+	count++
+	runStart = length
+	flush()
+}
+
+// findFinderPatterns scans every row and column of bitmap for the finder
+// pattern's characteristic 1:1:3:1:1 dark/light ratio, clusters the hits,
+// and returns the top-left, top-right, and bottom-left finder centers along
+// with the average per-module pixel size derived from those same hits.
+func findFinderPatterns(bitmap [][]bool, w, h int) (tl, tr, bl point, moduleSize float64, err error) {
+	var candidates []finderHit
+
+	for y := 0; y < h; y++ {
+		row := y
+		scanLineForFinders(func(x int) bool { return bitmap[row][x] }, w, func(c float64) point {
+			return point{c, float64(row)}
+		}, &candidates)
+	}
+	for x := 0; x < w; x++ {
+		col := x
+		scanLineForFinders(func(y int) bool { return bitmap[y][col] }, h, func(c float64) point {
+			return point{float64(col), c}
+		}, &candidates)
+	}
+
+	if len(candidates) == 0 {
+		return point{}, point{}, point{}, 0, errors.New("qrcode: no finder pattern candidates found")
+	}
+
+	clusters := clusterHits(candidates)
+	if len(clusters) < 3 {
+		return point{}, point{}, point{}, 0, errors.New("qrcode: could not locate three finder patterns")
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].votes > clusters[j].votes })
+	top3 := clusters[:3]
+
+	pts := []point{top3[0].center(), top3[1].center(), top3[2].center()}
+	tl, tr, bl, err = orderFinderPatterns(pts)
+	if err != nil {
+		return point{}, point{}, point{}, 0, err
+	}
+
+	moduleSize = (top3[0].moduleSize() + top3[1].moduleSize() + top3[2].moduleSize()) / 3
+	return tl, tr, bl, moduleSize, nil
+}
+
+// clusterHits merges candidate hits that are close together (within a
+// coarse distance threshold) into weighted clusters.
+func clusterHits(hits []finderHit) []*finderCluster {
+	var clusters []*finderCluster
+	const mergeDist = 6.0
+	for _, hit := range hits {
+		merged := false
+		for _, c := range clusters {
+			if hit.pt.dist(c.center()) < mergeDist {
+				c.sumX += hit.pt.X
+				c.sumY += hit.pt.Y
+				c.sumUnit += hit.unit
+				c.votes++
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			clusters = append(clusters, &finderCluster{sumX: hit.pt.X, sumY: hit.pt.Y, sumUnit: hit.unit, votes: 1})
+		}
+	}
+	return clusters
+}
+
+// orderFinderPatterns labels three finder centers as top-left, top-right,
+// and bottom-left. The top-left pattern is the vertex of the right angle
+// formed by the three points (i.e. it is not part of the longest of the
+// three pairwise distances); the other two are distinguished by the sign of
+// their cross product relative to top-left.
+func orderFinderPatterns(pts []point) (tl, tr, bl point, err error) {
+	if len(pts) != 3 {
+		return point{}, point{}, point{}, errors.New("qrcode: expected exactly three finder centers")
+	}
+	d01 := pts[0].dist(pts[1])
+	d02 := pts[0].dist(pts[2])
+	d12 := pts[1].dist(pts[2])
+
+	var a, b, c point
+	switch {
+	case d01 >= d02 && d01 >= d12:
+		a, b, c = pts[2], pts[0], pts[1] // a = top-left candidate
+	case d02 >= d01 && d02 >= d12:
+		a, b, c = pts[1], pts[0], pts[2]
+	default:
+		a, b, c = pts[0], pts[1], pts[2]
+	}
+
+	// a is top-left. Use the cross product of (b-a) x (c-a) to decide which
+	// of b, c is top-right (in image space, y grows downward).
+	cross := (b.X-a.X)*(c.Y-a.Y) - (b.Y-a.Y)*(c.X-a.X)
+	if cross < 0 {
+		b, c = c, b
+	}
+	return a, b, c, nil
+}
+
+// estimateVersion infers the symbol's module dimension and version from the
+// pixel distance between the top-left and top-right finder centers and the
+// previously estimated module size, then rounds to the nearest valid
+// version (size = 21 + 4*(v-1)).
+func estimateVersion(tl, tr point, moduleSize float64) (size, version int, err error) {
+	modulesBetween := tl.dist(tr) / moduleSize
+	sizeEstimate := modulesBetween + 7
+	version = int(math.Round((sizeEstimate-21)/4)) + 1
+	if version < 1 {
+		version = 1
+	}
+	if version > 40 {
+		version = 40
+	}
+	size = 21 + 4*(version-1)
+	return size, version, nil
+}
+
+// estimateFourthCorner estimates the bottom-right corner of the symbol
+// (where there is no finder pattern) via the parallelogram law: in a
+// roughly rectangular layout, bottomRight = topRight + bottomLeft - topLeft.
+func estimateFourthCorner(tl, tr, bl point) point {
+	return tr.add(bl).sub(tl)
+}
+
+// estimateAlignmentCenter projects the module coordinate (loc, loc) through
+// the grid defined by the three finder-pattern centers (tl sits at module
+// (3,3)) to predict where the bottom-right alignment pattern's center falls
+// in pixel space. This is much tighter than the fourth-corner parallelogram
+// estimate, which lands 3 modules further out along each axis than the true
+// pattern center and can pull locateAlignmentPattern's search onto a false
+// match near the symbol's actual corner.
+func estimateAlignmentCenter(tl, tr, bl point, moduleSize float64, loc int) point {
+	ux := tr.sub(tl)
+	ux = scalePoint(ux, 1/vecLen(ux))
+	uy := bl.sub(tl)
+	uy = scalePoint(uy, 1/vecLen(uy))
+	d := moduleSize * (float64(loc) - 3)
+	return tl.add(scalePoint(ux, d)).add(scalePoint(uy, d))
+}
+
+// locateAlignmentPattern searches a small window around the estimated
+// bottom-right alignment pattern location for a true 1:1:1:1:1 dark-light
+// run (the 5x5 alignment pattern's cross-section), returning a refined
+// center if found.
+func locateAlignmentPattern(bitmap [][]bool, w, h int, estimate point, moduleSize float64) (point, bool) {
+	searchRadius := int(moduleSize * 3)
+	if searchRadius < 4 {
+		searchRadius = 4
+	}
+	cx, cy := int(estimate.X), int(estimate.Y)
+
+	bestDist := math.MaxFloat64
+	var best point
+	found := false
+
+	for y := cy - searchRadius; y <= cy+searchRadius; y++ {
+		if y < 0 || y >= h {
+			continue
+		}
+		for x := cx - searchRadius; x <= cx+searchRadius; x++ {
+			if x < 0 || x >= w || !bitmap[y][x] {
+				continue
+			}
+			if !looksLikeAlignmentCenter(bitmap, w, h, x, y, moduleSize) {
+				continue
+			}
+			d := estimate.dist(point{float64(x), float64(y)})
+			if d < bestDist {
+				bestDist = d
+				best = point{float64(x), float64(y)}
+				found = true
+			}
+		}
+	}
+	return best, found
+}
+
+// looksLikeAlignmentCenter checks whether (x, y) sits at the center of a
+// dark-light-dark run along both axes, consistent with the alignment
+// pattern's 1:1:1 (module-wide) cross-section as seen from its middle.
+func looksLikeAlignmentCenter(bitmap [][]bool, w, h, x, y int, moduleSize float64) bool {
+	half := int(moduleSize*2.5) + 1
+	if x-half < 0 || x+half >= w || y-half < 0 || y+half >= h {
+		return false
+	}
+	// Expect dark at center, light at +-1 module, dark again at +-2 modules.
+	unit := int(moduleSize + 0.5)
+	if unit < 1 {
+		unit = 1
+	}
+	checks := []struct {
+		dx, dy int
+		dark   bool
+	}{
+		{0, 0, true},
+		{unit, 0, false}, {-unit, 0, false}, {0, unit, false}, {0, -unit, false},
+		{2 * unit, 0, true}, {-2 * unit, 0, true}, {0, 2 * unit, true}, {0, -2 * unit, true},
+	}
+	for _, chk := range checks {
+		if bitmap[y+chk.dy][x+chk.dx] != chk.dark {
+			return false
+		}
+	}
+	return true
+}
+
+// computeOuterCorners projects the finder centers (and the bottom-right
+// reference point, which is either the parallelogram estimate or a refined
+// alignment-pattern center) out to the symbol's true outer corners (module
+// (0,0), (size,0), (size,size), (0,size) in module space). Finder centers
+// sit 3.5 modules inward from the symbol edge along each axis; brOffset is
+// the equivalent inward distance for br, which differs from 3.5 when br is
+// an alignment-pattern center rather than a finder center.
+func computeOuterCorners(tl, tr, bl, br point, moduleSize, brOffset float64) [4]point {
+	ux := tr.sub(tl)
+	ux = scalePoint(ux, 1/vecLen(ux))
+	uy := bl.sub(tl)
+	uy = scalePoint(uy, 1/vecLen(uy))
+
+	offset := moduleSize * 3.5
+	outTL := tl.sub(scalePoint(ux, offset)).sub(scalePoint(uy, offset))
+	outTR := tr.add(scalePoint(ux, offset)).sub(scalePoint(uy, offset))
+	outBL := bl.sub(scalePoint(ux, offset)).add(scalePoint(uy, offset))
+	outBR := br.add(scalePoint(ux, brOffset)).add(scalePoint(uy, brOffset))
+	return [4]point{outTL, outTR, outBR, outBL}
+}
+
+func vecLen(p point) float64              { return math.Hypot(p.X, p.Y) }
+func scalePoint(p point, s float64) point { return point{p.X * s, p.Y * s} }
+
+// perspectiveTransform maps unit-square coordinates (u, v), both in
+// [0,1], to pixel coordinates, per the closed-form square-to-quadrilateral
+// projective mapping (Heckbert, "Fundamentals of Texture Mapping").
+type perspectiveTransform struct {
+	a, b, c, d, e, f, g, h float64
+}
+
+func (t perspectiveTransform) apply(u, v float64) (x, y float64) {
+	denom := t.g*u + t.h*v + 1
+	x = (t.a*u + t.b*v + t.c) / denom
+	y = (t.d*u + t.e*v + t.f) / denom
+	return x, y
+}
+
+// quadFromSquare computes the projective transform mapping the unit square
+// (0,0),(1,0),(1,1),(0,1) to the quadrilateral p0,p1,p2,p3 (given in the
+// same corresponding order).
+func quadFromSquare(p0, p1, p2, p3 point) perspectiveTransform {
+	dx1 := p1.X - p2.X
+	dx2 := p3.X - p2.X
+	dx3 := p0.X - p1.X + p2.X - p3.X
+	dy1 := p1.Y - p2.Y
+	dy2 := p3.Y - p2.Y
+	dy3 := p0.Y - p1.Y + p2.Y - p3.Y
+
+	if dx3 == 0 && dy3 == 0 {
+		return perspectiveTransform{
+			a: p1.X - p0.X, b: p2.X - p1.X, c: p0.X,
+			d: p1.Y - p0.Y, e: p2.Y - p1.Y, f: p0.Y,
+		}
+	}
+
+	denom := dx1*dy2 - dx2*dy1
+	var g, h float64
+	if denom != 0 {
+		g = (dx3*dy2 - dx2*dy3) / denom
+		h = (dx1*dy3 - dx3*dy1) / denom
+	}
+	return perspectiveTransform{
+		a: p1.X - p0.X + g*p1.X,
+		b: p3.X - p0.X + h*p3.X,
+		c: p0.X,
+		d: p1.Y - p0.Y + g*p1.Y,
+		e: p3.Y - p0.Y + h*p3.Y,
+		f: p0.Y,
+		g: g,
+		h: h,
+	}
+}
+
+// sampleSymbol samples every module of a size x size symbol through the
+// perspective transform (module centers map to pixel coordinates) and
+// returns the dark/light value of each, along with the function-pattern
+// mask for that version.
+func sampleSymbol(bitmap [][]bool, w, h int, transform perspectiveTransform, size, version int) (modules, isFunction [][]bool) {
+	_, _, isFunction = newFunctionPatterns(version)
+	modules = make([][]bool, size)
+	for i := range modules {
+		modules[i] = make([]bool, size)
+	}
+
+	for row := 0; row < size; row++ {
+		for col := 0; col < size; col++ {
+			u := (float64(col) + 0.5) / float64(size)
+			v := (float64(row) + 0.5) / float64(size)
+			x, y := transform.apply(u, v)
+			xi, yi := int(math.Round(x)), int(math.Round(y))
+			if xi < 0 {
+				xi = 0
+			} else if xi >= w {
+				xi = w - 1
+			}
+			if yi < 0 {
+				yi = 0
+			} else if yi >= h {
+				yi = h - 1
+			}
+			modules[row][col] = bitmap[yi][xi]
+		}
+	}
+	return modules, isFunction
+}
+
+// readFormatInfo reads both 15-bit copies of the format information, BCH-
+// corrects the first copy (the one around the top-left finder) and falls
+// back to the second copy only when the first is more than 3 bits from
+// every valid codeword, and returns the EC level and mask pattern it encodes.
+func readFormatInfo(modules [][]bool, size int) (level, mask int, err error) {
+	get := func(r, c int) int {
+		if modules[r][c] {
+			return 1
+		}
+		return 0
+	}
+
+	rawA := 0
+	for i := 0; i < 15; i++ {
+		bit := 0
+		switch i {
+		case 0:
+			bit = get(0, 8)
+		case 1:
+			bit = get(1, 8)
+		case 2:
+			bit = get(2, 8)
+		case 3:
+			bit = get(3, 8)
+		case 4:
+			bit = get(4, 8)
+		case 5:
+			bit = get(5, 8)
+		case 6:
+			bit = get(7, 8)
+		case 7:
+			bit = get(8, 8)
+		case 8:
+			bit = get(8, 7)
+		case 9:
+			bit = get(8, 5)
+		case 10:
+			bit = get(8, 4)
+		case 11:
+			bit = get(8, 3)
+		case 12:
+			bit = get(8, 2)
+		case 13:
+			bit = get(8, 1)
+		case 14:
+			bit = get(8, 0)
+		}
+		rawA |= bit << i
+	}
+
+	rawB := 0
+	for i := 0; i < 15; i++ {
+		var bit int
+		if i < 8 {
+			bit = get(8, size-1-i)
+		} else {
+			bit = get(size-8+(i-8), 8)
+		}
+		rawB |= bit << i
+	}
+
+	data, err := decodeFormatData(rawA)
+	if err != nil {
+		data, err = decodeFormatData(rawB)
+		if err != nil {
+			return 0, 0, errors.New("qrcode: format information unrecoverable")
+		}
+	}
+
+	ecBits := (data >> 3) & 0x3
+	mask = data & 0x7
+	switch ecBits {
+	case 1:
+		level = LevelL
+	case 0:
+		level = LevelM
+	case 3:
+		level = LevelQ
+	case 2:
+		level = LevelH
+	default:
+		return 0, 0, errors.New("qrcode: invalid EC level bits in format information")
+	}
+	return level, mask, nil
+}
+
+// decodeFormatData finds the 5-bit format data value whose BCH-encoded
+// 15-bit codeword is closest (by Hamming distance, up to 3 bits) to raw.
+func decodeFormatData(raw int) (int, error) {
+	best := -1
+	bestDist := 99
+	for data := 0; data < 32; data++ {
+		candidate := calculateBCHFormat(data)
+		dist := popcount(candidate ^ raw)
+		if dist < bestDist {
+			bestDist = dist
+			best = data
+		}
+	}
+	if bestDist > 3 {
+		return 0, errors.New("qrcode: no valid format codeword within correction distance")
+	}
+	return best, nil
+}
+
+// readVersionInfo reads both 18-bit copies of the version information (for
+// V7+ symbols) and BCH-corrects to the nearest valid version.
+func readVersionInfo(modules [][]bool, size int) (int, error) {
+	get := func(r, c int) int {
+		if modules[r][c] {
+			return 1
+		}
+		return 0
+	}
+
+	rawA, rawB := 0, 0
+	for i := 0; i < 18; i++ {
+		a := size - 11 + i%3
+		b := i / 3
+		rawA |= get(b, a) << i
+		rawB |= get(a, b) << i
+	}
+
+	best, bestDist := 0, 99
+	for v := 7; v <= 40; v++ {
+		candidate := computeVersionInfoBits(v)
+		for _, raw := range [2]int{rawA, rawB} {
+			dist := popcount(candidate ^ raw)
+			if dist < bestDist {
+				bestDist = dist
+				best = v
+			}
+		}
+	}
+	if bestDist > 3 {
+		return 0, errors.New("qrcode: version information unrecoverable")
+	}
+	return best, nil
+}
+
+func popcount(x int) int {
+	c := 0
+	for x != 0 {
+		c += x & 1
+		x >>= 1
+	}
+	return c
+}
+
+// extractCodewordBits walks the same zig-zag column order buildMatrix uses
+// to place data, reading back every non-function module's bit.
+func extractCodewordBits(modules, isFunction [][]bool, size int) []bool {
+	var bits []bool
+	for col := size - 1; col > 0; col -= 2 {
+		if col == 6 {
+			col--
+		}
+		for rowIter := 0; rowIter < size; rowIter++ {
+			r := rowIter
+			if ((col+1)/2)%2 == 0 {
+				r = size - 1 - rowIter
+			}
+			for c := col; c > col-2; c-- {
+				if !isFunction[r][c] {
+					bits = append(bits, modules[r][c])
+				}
+			}
+		}
+	}
+	return bits
+}
+
+// bitsToBytes packs a bool bit stream (MSB-first, 8 bits per byte) into
+// codeword values, dropping any trailing partial byte.
+func bitsToBytes(bits []bool) []int {
+	out := make([]int, 0, len(bits)/8)
+	for i := 0; i+8 <= len(bits); i += 8 {
+		val := 0
+		for j := 0; j < 8; j++ {
+			val <<= 1
+			if bits[i+j] {
+				val |= 1
+			}
+		}
+		out = append(out, val)
+	}
+	return out
+}
@@ -0,0 +1,173 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestCorrectErrorsFixesCorruptedBlock(t *testing.T) {
+	data := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	ec := CalculateECCodewords(data, 10)
+	block := append(append([]int{}, data...), ec...)
+
+	corrupted := append([]int{}, block...)
+	corrupted[2] ^= 0xFF
+	corrupted[7] ^= 0x55
+
+	corrected, err := CorrectErrors(corrupted, 10)
+	if err != nil {
+		t.Fatalf("CorrectErrors failed: %v", err)
+	}
+	for i, want := range data {
+		if corrected[i] != want {
+			t.Errorf("byte %d: expected %d, got %d", i, want, corrected[i])
+		}
+	}
+}
+
+func TestCorrectErrorsCleanBlock(t *testing.T) {
+	data := []int{9, 8, 7, 6, 5}
+	ec := CalculateECCodewords(data, 8)
+	block := append(append([]int{}, data...), ec...)
+
+	corrected, err := CorrectErrors(block, 8)
+	if err != nil {
+		t.Fatalf("CorrectErrors failed on clean block: %v", err)
+	}
+	for i, want := range data {
+		if corrected[i] != want {
+			t.Errorf("byte %d: expected %d, got %d", i, want, corrected[i])
+		}
+	}
+}
+
+func TestDeinterleaveBlocksInvertsInterleave(t *testing.T) {
+	data := make([]int, 62)
+	for i := range data {
+		data[i] = i
+	}
+	blocks := splitIntoBlocks(data, 4, 18)
+	interleaved := interleaveBlocks(blocks)
+
+	back := deinterleaveBlocks(interleaved, 4, 18, len(data))
+	if len(back) != len(blocks) {
+		t.Fatalf("expected %d blocks, got %d", len(blocks), len(back))
+	}
+	for i := range blocks {
+		if len(back[i].Data) != len(blocks[i].Data) {
+			t.Fatalf("block %d: data length mismatch: %d vs %d", i, len(back[i].Data), len(blocks[i].Data))
+		}
+		for j := range blocks[i].Data {
+			if back[i].Data[j] != blocks[i].Data[j] {
+				t.Errorf("block %d data[%d]: expected %d, got %d", i, j, blocks[i].Data[j], back[i].Data[j])
+			}
+		}
+		for j := range blocks[i].EC {
+			if back[i].EC[j] != blocks[i].EC[j] {
+				t.Errorf("block %d ec[%d]: expected %d, got %d", i, j, blocks[i].EC[j], back[i].EC[j])
+			}
+		}
+	}
+}
+
+func TestDecodeImageRoundTrip(t *testing.T) {
+	content := "DecodeImage end-to-end"
+
+	qr, err := NewQRCode(content, LevelL)
+	if err != nil {
+		t.Fatalf("NewQRCode failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := qr.WritePNG(&buf, 10); err != nil {
+		t.Fatalf("WritePNG failed: %v", err)
+	}
+
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatalf("png.Decode failed: %v", err)
+	}
+
+	decoded, err := DecodeImage(img)
+	if err != nil {
+		t.Fatalf("DecodeImage failed: %v", err)
+	}
+	if decoded != content {
+		t.Errorf("expected %q, got %q", content, decoded)
+	}
+}
+
+// TestDecodeImageRoundTripLowVersions exercises image decode across V2-V7,
+// the versions that carry alignment patterns but still fit in a single
+// finder-triangle search window. This is the range the alignment-pattern
+// seeding in DecodeImage must get right: a bad seed there (see the
+// estimateAlignmentCenter doc comment) pulls locateAlignmentPattern onto a false match
+// near the symbol's corner instead of the real pattern.
+func TestDecodeImageRoundTripLowVersions(t *testing.T) {
+	// Alphanumeric content lengths that land on each version at LevelL.
+	lengths := map[int]int{2: 26, 3: 48, 4: 78, 5: 115, 6: 155, 7: 196}
+
+	for version := 2; version <= 7; version++ {
+		content := strings.Repeat("A", lengths[version])
+		t.Run(fmt.Sprintf("V%d", version), func(t *testing.T) {
+			qr, err := NewQRCode(content, LevelL)
+			if err != nil {
+				t.Fatalf("NewQRCode failed: %v", err)
+			}
+			if qr.Version != version {
+				t.Fatalf("expected version %d, got %d", version, qr.Version)
+			}
+
+			var buf bytes.Buffer
+			if err := qr.WritePNG(&buf, 6); err != nil {
+				t.Fatalf("WritePNG failed: %v", err)
+			}
+
+			img, err := png.Decode(&buf)
+			if err != nil {
+				t.Fatalf("png.Decode failed: %v", err)
+			}
+
+			decoded, err := DecodeImage(img)
+			if err != nil {
+				t.Fatalf("DecodeImage failed: %v", err)
+			}
+			if decoded != content {
+				t.Errorf("expected %q, got %q", content, decoded)
+			}
+		})
+	}
+}
+
+func TestDecodeSegmentsRoundTripsEncodeSegmentBody(t *testing.T) {
+	segments := []Segment{
+		{Mode: ModeNumeric, Data: []byte("12345")},
+		{Mode: ModeAlphanumeric, Data: []byte("HELLO WORLD")},
+		{Mode: ModeByte, Data: []byte("mixed Case 123!")},
+	}
+
+	buf := NewBitBuffer()
+	version := 10
+	for _, seg := range segments {
+		charCount := len(seg.Data)
+		buf.Put(seg.Mode, 4)
+		buf.Put(charCount, countIndicatorBits(seg.Mode, version))
+		if err := encodeSegmentBody(buf, seg.Mode, seg.Data); err != nil {
+			t.Fatalf("encodeSegmentBody failed: %v", err)
+		}
+	}
+	buf.Put(0, 4) // terminator
+
+	decoded, err := decodeSegments(buf.Bits, version)
+	if err != nil {
+		t.Fatalf("decodeSegments failed: %v", err)
+	}
+
+	want := "12345HELLO WORLDmixed Case 123!"
+	if decoded != want {
+		t.Errorf("expected %q, got %q", want, decoded)
+	}
+}
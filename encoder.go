@@ -6,11 +6,12 @@ import (
 
 // Mode indicators
 const (
-	ModeNumeric      = 1
-	ModeAlphanumeric = 2
-	ModeByte         = 4
-	ModeKanji        = 8
-	ModeECI          = 7
+	ModeNumeric          = 1
+	ModeAlphanumeric     = 2
+	ModeByte             = 4
+	ModeKanji            = 8
+	ModeECI              = 7
+	ModeStructuredAppend = 3
 )
 
 // ECC Levels
@@ -21,97 +22,142 @@ const (
 	LevelH = 2 // 30%
 )
 
-// VersionInfo Version 1-40 info
+// VersionInfo holds the per-(version, level) capacity and block-structure
+// data needed to build a symbol. Blocks is the total number of blocks
+// (across both groups) the data codewords are interleaved into; see
+// splitIntoBlocks and interleaveBlocks in version.go. The table itself is
+// built in version.go from the raw ISO/IEC 18004 Annex D arrays.
 type VersionInfo struct {
 	TotalCodewords int
-	ECCodewords    int
-	Blocks         int // Number of blocks in group 1 (simplified for V1-V2)
-	// For larger versions, there are groups. We will start with support for small versions.
-	// We will implement dynamic lookup or just support V1 and V2 for "create and read again".
-}
-
-// Simplified table for Version 1 and 2, Level L/M
-// Ref: https://www.thonky.com/qr-code-tutorial/error-correction-table
-var versionTable = map[int]map[int]VersionInfo{
-	1: {
-		LevelL: {TotalCodewords: 26, ECCodewords: 7, Blocks: 1},
-		LevelM: {TotalCodewords: 26, ECCodewords: 10, Blocks: 1},
-		LevelQ: {TotalCodewords: 26, ECCodewords: 13, Blocks: 1},
-		LevelH: {TotalCodewords: 26, ECCodewords: 17, Blocks: 1},
-	},
-	2: {
-		LevelL: {TotalCodewords: 44, ECCodewords: 10, Blocks: 1},
-		LevelM: {TotalCodewords: 44, ECCodewords: 16, Blocks: 1},
-		LevelQ: {TotalCodewords: 44, ECCodewords: 22, Blocks: 1},
-		LevelH: {TotalCodewords: 44, ECCodewords: 28, Blocks: 1},
-	},
-	3: {
-		LevelL: {TotalCodewords: 70, ECCodewords: 15, Blocks: 1},
-		LevelM: {TotalCodewords: 70, ECCodewords: 26, Blocks: 1},
-		LevelQ: {TotalCodewords: 70, ECCodewords: 36, Blocks: 2}, // split not implemented
-		LevelH: {TotalCodewords: 70, ECCodewords: 44, Blocks: 2}, // split not implemented
-	},
-	4: {
-		LevelL: {TotalCodewords: 100, ECCodewords: 20, Blocks: 1},
-		LevelM: {TotalCodewords: 100, ECCodewords: 36, Blocks: 2}, // split not implemented
-		LevelQ: {TotalCodewords: 100, ECCodewords: 52, Blocks: 2}, // split not implemented
-		LevelH: {TotalCodewords: 100, ECCodewords: 64, Blocks: 4}, // split not implemented
-	},
-	// Add more if needed.
+	ECCodewords    int // EC codewords per block
+	Blocks         int // Total number of blocks across both groups
 }
 
 type QRCode struct {
 	Version int
 	Level   int
 	Size    int // Dimension (21 + 4*(V-1))
+	Mask    int // Mask pattern actually used (0-7)
 	Modules [][]bool
 }
 
+// Options controls optional behavior of NewQRCodeWithOptions.
+type Options struct {
+	// Mask selects the mask pattern (0-7). Use -1 to auto-select the pattern
+	// with the lowest penalty score, per ISO/IEC 18004 6.8.
+	Mask int
+}
+
 // NewQRCode creates a matrix for the given string.
 // Currently defaults to Byte Mode.
 func NewQRCode(content string, level int) (*QRCode, error) {
-	// Analyze data and choose version.
-	// Start with V1, if not fit, go V2.
+	return NewQRCodeWithOptions(content, level, Options{Mask: -1})
+}
+
+// NewQRCodeWithOptions is like NewQRCode but allows overriding the mask
+// pattern via Options. Options.Mask of -1 auto-selects the pattern with the
+// lowest penalty score.
+//
+// The content is segmented automatically (see optimizeSegments) so that
+// runs of digits or alphanumeric characters are packed more densely than
+// Byte mode; use NewQRCodeSegments for manual control over segmentation.
+func NewQRCodeWithOptions(content string, level int, opts Options) (*QRCode, error) {
 	data := []byte(content)
 
+	// The optimal segmentation depends on the count-indicator widths, which
+	// depend on the version group, which depends on the segmentation. Seed
+	// with the smallest version group and refine until it stabilizes.
+	versionGuess := 1
+	var segments []Segment
 	var v int
 	var vInfo VersionInfo
-	found := false
+	for iter := 0; iter < 4; iter++ {
+		segments = optimizeSegments(data, versionGuess)
 
-	// Try versions 1 to 4
-	for ver := 1; ver <= 4; ver++ {
-		info := versionTable[ver][level]
-		if info.Blocks > 1 {
-			// Skip versions requiring interleaving for this simplified implementation
-			continue
+		newV, info, found := fitVersion(segments, level)
+		if !found {
+			return nil, errors.New("content too long for a V40 symbol at this error correction level")
 		}
-
-		// Capacity check
-		// Byte mode: 4 bits mode + 8 bits count (V1-9) + 8*len
-		// V1-9 count indicator is 8 bits.
-		totalDataBits := 4 + 8 + len(data)*8
-		if totalDataBits <= (info.TotalCodewords-info.ECCodewords)*8 {
-			v = ver
-			vInfo = info
-			found = true
+		if newV == versionGuess {
+			v, vInfo = newV, info
 			break
 		}
+		versionGuess = newV
+		v, vInfo = newV, info
+	}
+
+	finalMessage, err := encodeSegments(segments, v, vInfo)
+	if err != nil {
+		return nil, err
 	}
 
+	return buildMatrix(v, level, finalMessage, opts), nil
+}
+
+// NewQRCodeSegments builds a symbol from caller-supplied segments, bypassing
+// automatic mode detection. This allows manual control over segmentation,
+// e.g. forcing Kanji mode for pre-encoded Shift-JIS bytes, or pinning a
+// specific mode. The version is always the smallest that fits the segments.
+func NewQRCodeSegments(segments []Segment, level int) (*QRCode, error) {
+	v, vInfo, found := fitVersion(segments, level)
 	if !found {
-		return nil, errors.New("content too long or requires block interleaving (not implemented)")
+		return nil, errors.New("segments too long for a V40 symbol at this error correction level")
+	}
+
+	finalMessage, err := encodeSegments(segments, v, vInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildMatrix(v, level, finalMessage, Options{Mask: -1}), nil
+}
+
+// fitVersion returns the smallest version (1-40) whose data capacity, at
+// the given EC level, fits the given segments, along with its VersionInfo.
+func fitVersion(segments []Segment, level int) (int, VersionInfo, bool) {
+	for ver := 1; ver <= 40; ver++ {
+		info := versionTable[ver][level]
+		dataCapacity := info.TotalCodewords - info.ECCodewords*info.Blocks
+
+		totalBits := 0
+		for _, seg := range segments {
+			totalBits += 4 + countIndicatorBits(seg.Mode, ver) + segmentBodyBits(seg.Mode, len(seg.Data))
+		}
+		if totalBits <= dataCapacity*8 {
+			return ver, info, true
+		}
 	}
+	return 0, VersionInfo{}, false
+}
 
-	// Data Encoding
+// encodeSegments writes the mode header and body for each segment, then
+// terminates, pads, and applies Reed-Solomon block interleaving to produce
+// the final interleaved codeword sequence ready for matrix placement.
+func encodeSegments(segments []Segment, v int, vInfo VersionInfo) ([]int, error) {
 	bitBuffer := NewBitBuffer()
-	bitBuffer.Put(ModeByte, 4)
-	bitBuffer.Put(len(data), 8) // 8 bits for count in V1-V9
-	for _, b := range data {
-		bitBuffer.Put(int(b), 8)
+	for _, seg := range segments {
+		charCount := len(seg.Data)
+		if seg.Mode == ModeKanji {
+			charCount = len(seg.Data) / 2
+		}
+		bitBuffer.Put(seg.Mode, 4)
+		bitBuffer.Put(charCount, countIndicatorBits(seg.Mode, v))
+		if err := encodeSegmentBody(bitBuffer, seg.Mode, seg.Data); err != nil {
+			return nil, err
+		}
 	}
 
-	// Terminator and Padding
-	dataCapacityBits := (vInfo.TotalCodewords - vInfo.ECCodewords) * 8
+	return finishAndInterleave(bitBuffer, vInfo), nil
+}
+
+// finishAndInterleave terminates the bit stream, pads it out to the
+// version's full data capacity, splits it into bytes, and applies
+// Reed-Solomon block interleaving to produce the final codeword sequence
+// ready for matrix placement. Callers that build a bit stream themselves
+// (e.g. NewStructuredAppend, which prepends a Structured Append header
+// before the mode segments) share this tail with encodeSegments.
+func finishAndInterleave(bitBuffer *BitBuffer, vInfo VersionInfo) []int {
+	dataCapacityBits := (vInfo.TotalCodewords - vInfo.ECCodewords*vInfo.Blocks) * 8
 	if bitBuffer.Len() < dataCapacityBits {
 		// Terminator (up to 4 zeros)
 		term := 4
@@ -148,165 +194,20 @@ func NewQRCode(content string, level int) (*QRCode, error) {
 		dataCodewords = append(dataCodewords, val)
 	}
 
-	ecCodewords := CalculateECCodewords(dataCodewords, vInfo.ECCodewords)
-
-	finalMessage := append(dataCodewords, ecCodewords...)
+	blocks := splitIntoBlocks(dataCodewords, vInfo.Blocks, vInfo.ECCodewords)
+	return interleaveBlocks(blocks)
+}
 
-	// Matrix Construction
+// buildMatrix lays out finder/timing/alignment/version/format patterns,
+// places the given (already interleaved) codewords, selects or applies the
+// mask, and returns the finished symbol.
+func buildMatrix(v, level int, finalMessage []int, opts Options) *QRCode {
+	size, modules, isFunction := newFunctionPatterns(v)
 	qr := &QRCode{
 		Version: v,
 		Level:   level,
-		Size:    21 + 4*(v-1),
-	}
-	qr.Modules = make([][]bool, qr.Size)
-	for i := range qr.Modules {
-		qr.Modules[i] = make([]bool, qr.Size)
-	}
-
-	// We need a way to track which modules are function patterns (reserved)
-	isFunction := make([][]bool, qr.Size)
-	for i := range isFunction {
-		isFunction[i] = make([]bool, qr.Size)
-	}
-
-	// Finder Patterns
-	addFinderPattern := func(r, c int) {
-		for i := 0; i < 7; i++ {
-			for j := 0; j < 7; j++ {
-				if r+i >= qr.Size || c+j >= qr.Size || r+i < 0 || c+j < 0 {
-					continue
-				}
-				isFunction[r+i][c+j] = true
-				if i == 0 || i == 6 || j == 0 || j == 6 || (i >= 2 && i <= 4 && j >= 2 && j <= 4) {
-					qr.Modules[r+i][c+j] = true
-				} else {
-					qr.Modules[r+i][c+j] = false
-				}
-			}
-		}
-	}
-
-	addFinderPattern(0, 0)
-	addFinderPattern(0, qr.Size-7)
-	addFinderPattern(qr.Size-7, 0)
-
-	// Separators (white space around finders)
-	// Top Left
-	for i := 0; i < 8; i++ {
-		if i < qr.Size && 7 < qr.Size {
-			isFunction[i][7] = true
-			qr.Modules[i][7] = false
-		}
-		if i < qr.Size && 7 < qr.Size {
-			isFunction[7][i] = true
-			qr.Modules[7][i] = false
-		}
-	}
-	// Top Right
-	for i := 0; i < 8; i++ {
-		if i < qr.Size && qr.Size-8 >= 0 {
-			isFunction[i][qr.Size-8] = true
-			qr.Modules[i][qr.Size-8] = false
-		}
-		if qr.Size-1-i >= 0 && 7 < qr.Size {
-			isFunction[7][qr.Size-1-i] = true
-			qr.Modules[7][qr.Size-1-i] = false
-		}
-	}
-	// Bottom Left
-	for i := 0; i < 8; i++ {
-		if qr.Size-1-i >= 0 && 7 < qr.Size {
-			isFunction[qr.Size-1-i][7] = true
-			qr.Modules[qr.Size-1-i][7] = false
-		}
-		if i < qr.Size && qr.Size-8 >= 0 {
-			isFunction[qr.Size-8][i] = true
-			qr.Modules[qr.Size-8][i] = false
-		}
-	}
-
-	// Alignment Patterns (For V2+)
-	if v >= 2 {
-		// Locations depend on version. Simplified for V2-V4.
-		// V2: 6, 18
-		// V3: 6, 22
-		// V4: 6, 26
-		// Note: The '6' is implicitly handled by finder patterns exclusion usually, but we need to place at intersections.
-		// Locations list includes 6 but 6 overlaps with finder.
-
-		var locs []int
-		switch v {
-		case 2:
-			locs = []int{6, 18}
-		case 3:
-			locs = []int{6, 22}
-		case 4:
-			locs = []int{6, 26}
-		}
-
-		for _, cx := range locs {
-			for _, cy := range locs {
-				// If overlaps with finder patterns, skip.
-				// Finders are 0..8 (inclusive of separator)
-				if (cx < 9 && cy < 9) || (cx < 9 && cy > qr.Size-9) || (cx > qr.Size-9 && cy < 9) {
-					continue
-				}
-
-				// Draw Alignment Pattern 5x5
-				for i := -2; i <= 2; i++ {
-					for j := -2; j <= 2; j++ {
-						r, c := cy+i, cx+j
-						if !isFunction[r][c] {
-							isFunction[r][c] = true
-							if i == -2 || i == 2 || j == -2 || j == 2 || (i == 0 && j == 0) {
-								qr.Modules[r][c] = true
-							} else {
-								qr.Modules[r][c] = false
-							}
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Timing Patterns
-	for i := 8; i < qr.Size-8; i++ {
-		if !isFunction[6][i] {
-			isFunction[6][i] = true
-			qr.Modules[6][i] = (i%2 == 0)
-		}
-		if !isFunction[i][6] {
-			isFunction[i][6] = true
-			qr.Modules[i][6] = (i%2 == 0)
-		}
-	}
-
-	// Dark Module
-	isFunction[qr.Size-8][8] = true
-	qr.Modules[qr.Size-8][8] = true
-
-	// Reserve Format Information areas
-	// Around Top-Left Finder
-	for i := 0; i < 9; i++ {
-		if !isFunction[8][i] {
-			isFunction[8][i] = true
-		} // Horizontal
-		if !isFunction[i][8] {
-			isFunction[i][8] = true
-		} // Vertical
-	}
-	// Below Top-Right Finder
-	for i := 0; i < 8; i++ {
-		if !isFunction[8][qr.Size-1-i] {
-			isFunction[8][qr.Size-1-i] = true
-		}
-	}
-	// Right of Bottom-Left Finder
-	for i := 0; i < 7; i++ {
-		if !isFunction[qr.Size-1-i][8] {
-			isFunction[qr.Size-1-i][8] = true
-		}
+		Size:    size,
+		Modules: modules,
 	}
 
 	// Place Data
@@ -314,10 +215,6 @@ func NewQRCode(content string, level int) (*QRCode, error) {
 	idx := 0
 	totalBits := len(finalMessage) * 8
 
-	// Simple Mask Pattern 0: (row + col) % 2 == 0 (Checkerboard)
-	// We will use mask 0 strictly for now to simplify.
-	maskPattern := 0
-
 	// Helper to get bit from message
 	getBit := func(k int) bool {
 		byteIdx := k / 8
@@ -343,17 +240,25 @@ func NewQRCode(content string, level int) (*QRCode, error) {
 						bit = getBit(idx)
 						idx++
 					}
-					// Apply mask 0: (row + column) % 2 == 0
-					mask := (r+c)%2 == 0
-					if mask {
-						bit = !bit
-					}
 					qr.Modules[r][c] = bit
 				}
 			}
 		}
 	}
 
+	// Mask Selection
+	// qr.Modules currently holds the unmasked data modules (function patterns
+	// are already final). Either use the requested pattern or try all eight
+	// and keep the one with the lowest penalty score.
+	var maskPattern int
+	if opts.Mask >= 0 && opts.Mask <= 7 {
+		maskPattern = opts.Mask
+		qr.Modules = applyMask(qr.Modules, isFunction, maskPattern)
+	} else {
+		maskPattern, qr.Modules = selectBestMask(qr.Modules, isFunction)
+	}
+	qr.Mask = maskPattern
+
 	// Format Information
 	// ECC Level (2 bits) + Mask Pattern (3 bits)
 	// L=01, M=00, Q=11, H=10. Re-mapped:
@@ -448,7 +353,20 @@ func NewQRCode(content string, level int) (*QRCode, error) {
 	}
 	// Dark Module fixed at [Size-8][8] is already set
 
-	return qr, nil
+	// Version Information (V7+): 18 bits (6-bit version + 12-bit BCH code),
+	// written twice in mirrored 3x6 blocks, unmasked like the format bits.
+	if v >= 7 {
+		versionBits := computeVersionInfoBits(v)
+		for i := 0; i < 18; i++ {
+			bit := (versionBits>>i)&1 == 1
+			a := qr.Size - 11 + i%3
+			b := i / 3
+			setModule(b, a, bit)
+			setModule(a, b, bit)
+		}
+	}
+
+	return qr
 }
 
 func calculateBCHFormat(data int) int {
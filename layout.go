@@ -0,0 +1,130 @@
+package qrcode
+
+// newFunctionPatterns builds the fixed structural patterns of a symbol of
+// the given version: finder patterns, separators, alignment patterns,
+// timing patterns, the dark module, and reservations (but not yet values)
+// for the format- and version-information areas. It returns the module
+// dimension, the partially-filled module grid, and an isFunction mask
+// marking every cell that data placement must skip. Both buildMatrix
+// (encoding) and DecodeImage (decoding) share this so the two stay in
+// lock-step.
+func newFunctionPatterns(version int) (size int, modules, isFunction [][]bool) {
+	size = 21 + 4*(version-1)
+
+	modules = make([][]bool, size)
+	isFunction = make([][]bool, size)
+	for i := range modules {
+		modules[i] = make([]bool, size)
+		isFunction[i] = make([]bool, size)
+	}
+
+	// Finder Patterns
+	addFinderPattern := func(r, c int) {
+		for i := 0; i < 7; i++ {
+			for j := 0; j < 7; j++ {
+				if r+i >= size || c+j >= size || r+i < 0 || c+j < 0 {
+					continue
+				}
+				isFunction[r+i][c+j] = true
+				if i == 0 || i == 6 || j == 0 || j == 6 || (i >= 2 && i <= 4 && j >= 2 && j <= 4) {
+					modules[r+i][c+j] = true
+				} else {
+					modules[r+i][c+j] = false
+				}
+			}
+		}
+	}
+
+	addFinderPattern(0, 0)
+	addFinderPattern(0, size-7)
+	addFinderPattern(size-7, 0)
+
+	// Separators (white space around finders)
+	// Top Left
+	for i := 0; i < 8; i++ {
+		isFunction[i][7] = true
+		modules[i][7] = false
+		isFunction[7][i] = true
+		modules[7][i] = false
+	}
+	// Top Right
+	for i := 0; i < 8; i++ {
+		isFunction[i][size-8] = true
+		modules[i][size-8] = false
+		isFunction[7][size-1-i] = true
+		modules[7][size-1-i] = false
+	}
+	// Bottom Left
+	for i := 0; i < 8; i++ {
+		isFunction[size-1-i][7] = true
+		modules[size-1-i][7] = false
+		isFunction[size-8][i] = true
+		modules[size-8][i] = false
+	}
+
+	// Alignment Patterns (For V2+)
+	if locs, ok := alignmentPatternPositions[version]; ok {
+		for _, cx := range locs {
+			for _, cy := range locs {
+				// If overlaps with finder patterns, skip.
+				if (cx < 9 && cy < 9) || (cx < 9 && cy > size-9) || (cx > size-9 && cy < 9) {
+					continue
+				}
+
+				for i := -2; i <= 2; i++ {
+					for j := -2; j <= 2; j++ {
+						r, c := cy+i, cx+j
+						if !isFunction[r][c] {
+							isFunction[r][c] = true
+							if i == -2 || i == 2 || j == -2 || j == 2 || (i == 0 && j == 0) {
+								modules[r][c] = true
+							} else {
+								modules[r][c] = false
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Timing Patterns
+	for i := 8; i < size-8; i++ {
+		if !isFunction[6][i] {
+			isFunction[6][i] = true
+			modules[6][i] = i%2 == 0
+		}
+		if !isFunction[i][6] {
+			isFunction[i][6] = true
+			modules[i][6] = i%2 == 0
+		}
+	}
+
+	// Dark Module
+	isFunction[size-8][8] = true
+	modules[size-8][8] = true
+
+	// Reserve Format Information areas
+	for i := 0; i < 9; i++ {
+		isFunction[8][i] = true
+		isFunction[i][8] = true
+	}
+	for i := 0; i < 8; i++ {
+		isFunction[8][size-1-i] = true
+	}
+	for i := 0; i < 7; i++ {
+		isFunction[size-1-i][8] = true
+	}
+
+	// Reserve Version Information areas (V7+)
+	if version >= 7 {
+		for i := 0; i < 18; i++ {
+			a := size - 11 + i%3
+			b := i / 3
+			isFunction[b][a] = true
+			isFunction[a][b] = true
+		}
+	}
+
+	return size, modules, isFunction
+}
@@ -0,0 +1,188 @@
+package qrcode
+
+// Mask pattern predicates, as defined by the QR spec (ISO/IEC 18004 Table 14).
+// Each predicate reports whether the module at (row, col) should be flipped.
+var maskPredicates = [8]func(r, c int) bool{
+	func(r, c int) bool { return (r+c)%2 == 0 },
+	func(r, c int) bool { return r%2 == 0 },
+	func(r, c int) bool { return c%3 == 0 },
+	func(r, c int) bool { return (r+c)%3 == 0 },
+	func(r, c int) bool { return (r/2+c/3)%2 == 0 },
+	func(r, c int) bool { return (r*c)%2+(r*c)%3 == 0 },
+	func(r, c int) bool { return ((r*c)%2+(r*c)%3)%2 == 0 },
+	func(r, c int) bool { return ((r+c)%2+(r*c)%3)%2 == 0 },
+}
+
+// applyMask returns a copy of modules with the given mask pattern applied to
+// every non-function module.
+func applyMask(modules, isFunction [][]bool, pattern int) [][]bool {
+	predicate := maskPredicates[pattern]
+	size := len(modules)
+	out := make([][]bool, size)
+	for r := 0; r < size; r++ {
+		out[r] = make([]bool, size)
+		for c := 0; c < size; c++ {
+			bit := modules[r][c]
+			if !isFunction[r][c] && predicate(r, c) {
+				bit = !bit
+			}
+			out[r][c] = bit
+		}
+	}
+	return out
+}
+
+// maskPenalty computes the total QR penalty score (N1+N2+N3+N4) for a
+// finished (masked) module matrix, as defined by ISO/IEC 18004 6.8.2.
+func maskPenalty(modules [][]bool) int {
+	return penaltyN1(modules) + penaltyN2(modules) + penaltyN3(modules) + penaltyN4(modules)
+}
+
+// penaltyN1 scores runs of 5 or more same-colored modules in a row or column.
+// Each run of length L >= 5 costs 3 + (L-5).
+func penaltyN1(modules [][]bool) int {
+	size := len(modules)
+	total := 0
+
+	scanRun := func(get func(i int) bool, n int) int {
+		score := 0
+		runLen := 1
+		for i := 1; i < n; i++ {
+			if get(i) == get(i-1) {
+				runLen++
+				continue
+			}
+			if runLen >= 5 {
+				score += 3 + (runLen - 5)
+			}
+			runLen = 1
+		}
+		if runLen >= 5 {
+			score += 3 + (runLen - 5)
+		}
+		return score
+	}
+
+	for r := 0; r < size; r++ {
+		row := r
+		total += scanRun(func(c int) bool { return modules[row][c] }, size)
+	}
+	for c := 0; c < size; c++ {
+		col := c
+		total += scanRun(func(r int) bool { return modules[r][col] }, size)
+	}
+	return total
+}
+
+// penaltyN2 scores every 2x2 block of modules that are all the same color,
+// at 3 points per block.
+func penaltyN2(modules [][]bool) int {
+	size := len(modules)
+	score := 0
+	for r := 0; r < size-1; r++ {
+		for c := 0; c < size-1; c++ {
+			v := modules[r][c]
+			if modules[r][c+1] == v && modules[r+1][c] == v && modules[r+1][c+1] == v {
+				score += 3
+			}
+		}
+	}
+	return score
+}
+
+// finderLikePattern is the 1:1:3:1:1 ratio run (dark:light:dark:light:dark as
+// 1011101) padded with four light modules, that looks like a finder pattern
+// when it appears mid-symbol. Either orientation costs 40 points.
+var finderLikePattern = []bool{true, false, true, true, true, false, true, false, false, false, false}
+
+// penaltyN3 scores occurrences of the finder-lookalike pattern (or its
+// reverse) in any row or column, at 40 points per occurrence.
+func penaltyN3(modules [][]bool) int {
+	size := len(modules)
+	patLen := len(finderLikePattern)
+	score := 0
+
+	matches := func(get func(i int) bool, start int) bool {
+		for i := 0; i < patLen; i++ {
+			if get(start+i) != finderLikePattern[i] {
+				return false
+			}
+		}
+		return true
+	}
+	matchesReversed := func(get func(i int) bool, start int) bool {
+		for i := 0; i < patLen; i++ {
+			if get(start+i) != finderLikePattern[patLen-1-i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for r := 0; r < size; r++ {
+		row := r
+		get := func(c int) bool { return modules[row][c] }
+		for c := 0; c+patLen <= size; c++ {
+			if matches(get, c) {
+				score += 40
+			}
+			if matchesReversed(get, c) {
+				score += 40
+			}
+		}
+	}
+	for c := 0; c < size; c++ {
+		col := c
+		get := func(r int) bool { return modules[r][col] }
+		for r := 0; r+patLen <= size; r++ {
+			if matches(get, r) {
+				score += 40
+			}
+			if matchesReversed(get, r) {
+				score += 40
+			}
+		}
+	}
+	return score
+}
+
+// penaltyN4 scores how far the proportion of dark modules deviates from 50%,
+// at 10 points per 5% step.
+func penaltyN4(modules [][]bool) int {
+	size := len(modules)
+	dark := 0
+	for r := 0; r < size; r++ {
+		for c := 0; c < size; c++ {
+			if modules[r][c] {
+				dark++
+			}
+		}
+	}
+	percentDark := dark * 100 / (size * size)
+	diff := percentDark - 50
+	if diff < 0 {
+		diff = -diff
+	}
+	return (diff / 5) * 10
+}
+
+// selectBestMask tries all eight mask patterns against the unmasked data
+// modules and returns the pattern index with the lowest total penalty score,
+// along with the resulting masked matrix.
+func selectBestMask(modules, isFunction [][]bool) (int, [][]bool) {
+	bestPattern := 0
+	var bestModules [][]bool
+	bestScore := -1
+
+	for pattern := 0; pattern < 8; pattern++ {
+		candidate := applyMask(modules, isFunction, pattern)
+		score := maskPenalty(candidate)
+		if bestScore == -1 || score < bestScore {
+			bestScore = score
+			bestPattern = pattern
+			bestModules = candidate
+		}
+	}
+
+	return bestPattern, bestModules
+}
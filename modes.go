@@ -0,0 +1,412 @@
+package qrcode
+
+import (
+	"errors"
+	"strings"
+)
+
+// Segment is one mode-tagged chunk of a symbol's data, as produced by
+// optimizeSegments or built manually for NewQRCodeSegments. For all modes
+// except Kanji, Data holds the raw characters to encode. For ModeKanji,
+// Data holds raw Shift-JIS bytes, two per character.
+type Segment struct {
+	Mode int
+	Data []byte
+}
+
+// alphanumericCharset is the 45-character set usable in Alphanumeric mode,
+// in index order (ISO/IEC 18004 Table 5).
+const alphanumericCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+func isAlphanumericChar(b byte) bool {
+	return strings.IndexByte(alphanumericCharset, b) >= 0
+}
+
+// isKanjiPair reports whether the two bytes starting at data[i] form a
+// valid Shift-JIS double-byte code in one of the two Kanji ranges.
+func isKanjiPair(data []byte, i int) bool {
+	if i+1 >= len(data) {
+		return false
+	}
+	val := uint16(data[i])<<8 | uint16(data[i+1])
+	return (val >= 0x8140 && val <= 0x9FFC) || (val >= 0xE040 && val <= 0xEBBF)
+}
+
+// segmentBodyBits returns the number of bits the body (excluding the mode
+// indicator and count indicator) of a segment with the given mode and data
+// length occupies. length is a byte count, except for ModeKanji where it is
+// already known to be even (pairs of bytes).
+func segmentBodyBits(mode, length int) int {
+	switch mode {
+	case ModeNumeric:
+		groups, rem := length/3, length%3
+		bits := groups * 10
+		switch rem {
+		case 1:
+			bits += 4
+		case 2:
+			bits += 7
+		}
+		return bits
+	case ModeAlphanumeric:
+		groups, rem := length/2, length%2
+		bits := groups * 11
+		if rem == 1 {
+			bits += 6
+		}
+		return bits
+	case ModeKanji:
+		return (length / 2) * 13
+	default: // ModeByte
+		return length * 8
+	}
+}
+
+// encodeSegmentBody writes the body bits (excluding mode and count
+// indicators) of a segment to buf.
+func encodeSegmentBody(buf *BitBuffer, mode int, data []byte) error {
+	switch mode {
+	case ModeNumeric:
+		for i := 0; i < len(data); i += 3 {
+			end := i + 3
+			if end > len(data) {
+				end = len(data)
+			}
+			chunk := data[i:end]
+			val := 0
+			for _, b := range chunk {
+				if !isDigit(b) {
+					return errors.New("qrcode: invalid digit in numeric segment")
+				}
+				val = val*10 + int(b-'0')
+			}
+			bits := 10
+			switch len(chunk) {
+			case 1:
+				bits = 4
+			case 2:
+				bits = 7
+			}
+			buf.Put(val, bits)
+		}
+	case ModeAlphanumeric:
+		for i := 0; i < len(data); i += 2 {
+			c1 := strings.IndexByte(alphanumericCharset, data[i])
+			if c1 < 0 {
+				return errors.New("qrcode: invalid character in alphanumeric segment")
+			}
+			if i+1 < len(data) {
+				c2 := strings.IndexByte(alphanumericCharset, data[i+1])
+				if c2 < 0 {
+					return errors.New("qrcode: invalid character in alphanumeric segment")
+				}
+				buf.Put(c1*45+c2, 11)
+			} else {
+				buf.Put(c1, 6)
+			}
+		}
+	case ModeKanji:
+		if len(data)%2 != 0 {
+			return errors.New("qrcode: kanji segment must have an even number of bytes")
+		}
+		for i := 0; i < len(data); i += 2 {
+			if !isKanjiPair(data, i) {
+				return errors.New("qrcode: byte pair out of Shift-JIS Kanji range")
+			}
+			val := uint16(data[i])<<8 | uint16(data[i+1])
+			if val >= 0x8140 && val <= 0x9FFC {
+				val -= 0x8140
+			} else {
+				val -= 0xC140
+			}
+			bits := int(val>>8)*0xC0 + int(val&0xFF)
+			buf.Put(bits, 13)
+		}
+	default: // ModeByte
+		for _, b := range data {
+			buf.Put(int(b), 8)
+		}
+	}
+	return nil
+}
+
+// optimizeSegments splits data into the minimum-bit sequence of mode
+// segments for the given version (its version group determines the width
+// of each mode's count indicator). It is a dynamic program over byte
+// positions: at each position the state is the mode of the segment
+// currently being extended, and the transition either extends that
+// segment (no new header) or closes it and opens a new one (paying the
+// 4-bit mode indicator plus the count-indicator overhead).
+func optimizeSegments(data []byte, version int) []Segment {
+	if len(data) == 0 {
+		return nil
+	}
+
+	type unit struct {
+		modes   int // bitmask of ModeNumeric|ModeAlphanumeric|ModeKanji|ModeByte
+		byteLen int
+	}
+
+	units := make([]unit, 0, len(data))
+	for i := 0; i < len(data); {
+		if isKanjiPair(data, i) {
+			units = append(units, unit{modes: ModeKanji | ModeByte, byteLen: 2})
+			i += 2
+			continue
+		}
+		m := ModeByte
+		if isDigit(data[i]) {
+			m |= ModeNumeric | ModeAlphanumeric
+		} else if isAlphanumericChar(data[i]) {
+			m |= ModeAlphanumeric
+		}
+		units = append(units, unit{modes: m, byteLen: 1})
+		i++
+	}
+
+	// The four candidate modes, in a fixed order used to index the small
+	// per-position arrays below.
+	modes := [4]int{ModeNumeric, ModeAlphanumeric, ModeByte, ModeKanji}
+	const inf = 1 << 30
+	n := len(units)
+
+	// bits[i][k]: minimum total bits for a path over units[:i] that ends
+	// with an open, still-extendable run of modes[k]. runLen[i][k] is that
+	// run's length in characters. bestAt[i] / bestAtMode[i] track the
+	// cheapest way to reach position i by any mode (used as the baseline
+	// cost when starting a fresh segment at i).
+	bits := make([][4]int, n+1)
+	runLen := make([][4]int, n+1)
+	bestAt := make([]int, n+1)
+	bestAtMode := make([]int, n+1)
+	for k := range bits[0] {
+		bits[0][k] = inf
+	}
+	bestAt[0] = 0
+
+	header := func(mode int) int { return 4 + countIndicatorBits(mode, version) }
+
+	for i := 0; i < n; i++ {
+		u := units[i]
+		for k, mode := range modes {
+			if u.modes&mode == 0 {
+				bits[i+1][k] = inf
+				continue
+			}
+			chars := 1 // each unit (byte, or Kanji byte-pair) is one character
+
+			extendCost := inf
+			if bits[i][k] < inf {
+				oldLen := runLen[i][k]
+				extendCost = bits[i][k] - segmentBodyBits(mode, lengthInUnits(mode, oldLen)) +
+					segmentBodyBits(mode, lengthInUnits(mode, oldLen+chars))
+			}
+
+			startCost := bestAt[i] + header(mode) + segmentBodyBits(mode, lengthInUnits(mode, chars))
+
+			// Byte mode counts bytes, not characters: a Kanji byte-pair unit
+			// costs both of its bytes there.
+			if mode == ModeByte && u.byteLen > 1 {
+				extra := segmentBodyBits(ModeByte, u.byteLen-1)
+				if extendCost < inf {
+					extendCost += extra
+				}
+				startCost += extra
+			}
+
+			if extendCost <= startCost {
+				bits[i+1][k] = extendCost
+				runLen[i+1][k] = runLen[i][k] + chars
+			} else {
+				bits[i+1][k] = startCost
+				runLen[i+1][k] = chars
+			}
+		}
+
+		bestAt[i+1] = inf
+		for k := range modes {
+			if bits[i+1][k] < bestAt[i+1] {
+				bestAt[i+1] = bits[i+1][k]
+				bestAtMode[i+1] = modes[k]
+			}
+		}
+	}
+
+	// Reconstruct segments by walking backward: at each position, the
+	// cheapest mode's run length tells us exactly where that run began.
+	type span struct {
+		mode         int
+		startU, endU int // unit index range [startU, endU)
+	}
+	var spans []span
+	pos := n
+	mode := bestAtMode[n]
+	for pos > 0 {
+		k := modeIndex(modes, mode)
+		length := runLen[pos][k]
+		start := pos - length
+		spans = append(spans, span{mode: mode, startU: start, endU: pos})
+		pos = start
+		if pos > 0 {
+			mode = bestAtMode[pos]
+		}
+	}
+
+	// Map unit indices back to byte offsets and materialize segments in
+	// forward order.
+	unitOffset := make([]int, n+1)
+	for i, u := range units {
+		unitOffset[i+1] = unitOffset[i] + u.byteLen
+	}
+
+	segments := make([]Segment, 0, len(spans))
+	for i := len(spans) - 1; i >= 0; i-- {
+		s := spans[i]
+		segments = append(segments, Segment{
+			Mode: s.mode,
+			Data: append([]byte{}, data[unitOffset[s.startU]:unitOffset[s.endU]]...),
+		})
+	}
+	return mergeAdjacentSegments(segments)
+}
+
+// modeIndex returns the index of mode within modes.
+func modeIndex(modes [4]int, mode int) int {
+	for i, m := range modes {
+		if m == mode {
+			return i
+		}
+	}
+	return 0
+}
+
+// lengthInUnits converts a character count to the "length" argument
+// segmentBodyBits expects: the raw character count for every mode except
+// Kanji, whose body-bit formula is defined in terms of byte pairs.
+func lengthInUnits(mode, chars int) int {
+	if mode == ModeKanji {
+		return chars * 2
+	}
+	return chars
+}
+
+// bitReader reads fixed-width big-endian fields off a flat bit stream, the
+// mirror image of BitBuffer.Put.
+type bitReader struct {
+	bits []bool
+	pos  int
+}
+
+func (r *bitReader) read(n int) int {
+	val := 0
+	for i := 0; i < n; i++ {
+		val <<= 1
+		if r.pos < len(r.bits) && r.bits[r.pos] {
+			val |= 1
+		}
+		r.pos++
+	}
+	return val
+}
+
+func (r *bitReader) remaining() int {
+	if r.pos >= len(r.bits) {
+		return 0
+	}
+	return len(r.bits) - r.pos
+}
+
+// decodeSegments parses a decoded (and error-corrected) codeword bit stream
+// back into the original string. It is the inverse of encodeSegments: it
+// reads mode indicators and walks the matching body decoder until it hits
+// the terminator (mode 0) or runs out of bits.
+func decodeSegments(bits []bool, version int) (string, error) {
+	r := &bitReader{bits: bits}
+	var out []byte
+
+	for r.remaining() >= 4 {
+		mode := r.read(4)
+		if mode == 0 {
+			break
+		}
+
+		switch mode {
+		case ModeNumeric:
+			count := r.read(countIndicatorBits(ModeNumeric, version))
+			for count > 0 {
+				switch {
+				case count >= 3:
+					val := r.read(10)
+					out = append(out, byte('0'+val/100), byte('0'+(val/10)%10), byte('0'+val%10))
+					count -= 3
+				case count == 2:
+					val := r.read(7)
+					out = append(out, byte('0'+val/10), byte('0'+val%10))
+					count = 0
+				default:
+					val := r.read(4)
+					out = append(out, byte('0'+val))
+					count = 0
+				}
+			}
+		case ModeAlphanumeric:
+			count := r.read(countIndicatorBits(ModeAlphanumeric, version))
+			for count > 0 {
+				if count >= 2 {
+					val := r.read(11)
+					out = append(out, alphanumericCharset[val/45], alphanumericCharset[val%45])
+					count -= 2
+				} else {
+					val := r.read(6)
+					out = append(out, alphanumericCharset[val])
+					count = 0
+				}
+			}
+		case ModeByte:
+			count := r.read(countIndicatorBits(ModeByte, version))
+			for i := 0; i < count; i++ {
+				out = append(out, byte(r.read(8)))
+			}
+		case ModeKanji:
+			count := r.read(countIndicatorBits(ModeKanji, version))
+			for i := 0; i < count; i++ {
+				val := r.read(13)
+				assembled := (val/0xC0)<<8 | (val % 0xC0)
+				full := assembled + 0x8140
+				if full > 0x9FFC {
+					full = assembled + 0xC140
+				}
+				out = append(out, byte(full>>8), byte(full&0xFF))
+			}
+		case ModeECI:
+			r.read(8) // single-byte ECI designator; value itself is not tracked
+		default:
+			return "", errors.New("qrcode: unsupported mode indicator in data stream")
+		}
+	}
+
+	return string(out), nil
+}
+
+// mergeAdjacentSegments merges consecutive segments that ended up with the
+// same mode (this can happen when extending vs. restarting the same mode
+// tied in cost at a boundary).
+func mergeAdjacentSegments(segments []Segment) []Segment {
+	if len(segments) == 0 {
+		return segments
+	}
+	merged := []Segment{segments[0]}
+	for _, s := range segments[1:] {
+		last := &merged[len(merged)-1]
+		if last.Mode == s.Mode {
+			last.Data = append(last.Data, s.Data...)
+			continue
+		}
+		merged = append(merged, s)
+	}
+	return merged
+}
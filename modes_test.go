@@ -0,0 +1,66 @@
+package qrcode
+
+import "testing"
+
+func TestOptimizeSegmentsPicksNumericForDigits(t *testing.T) {
+	segments := optimizeSegments([]byte("0123456789"), 1)
+	if len(segments) != 1 {
+		t.Fatalf("expected 1 segment, got %d: %+v", len(segments), segments)
+	}
+	if segments[0].Mode != ModeNumeric {
+		t.Errorf("expected ModeNumeric, got %d", segments[0].Mode)
+	}
+	if string(segments[0].Data) != "0123456789" {
+		t.Errorf("unexpected segment data: %q", segments[0].Data)
+	}
+}
+
+func TestOptimizeSegmentsMixedContent(t *testing.T) {
+	// A numeric run followed by lowercase letters, which only Byte mode covers.
+	segments := optimizeSegments([]byte("12345hello"), 1)
+
+	var rebuilt []byte
+	for _, seg := range segments {
+		rebuilt = append(rebuilt, seg.Data...)
+	}
+	if string(rebuilt) != "12345hello" {
+		t.Fatalf("segments don't reconstruct original data: %q", rebuilt)
+	}
+	if segments[0].Mode != ModeNumeric {
+		t.Errorf("expected the leading run to use ModeNumeric, got %d", segments[0].Mode)
+	}
+	last := segments[len(segments)-1]
+	if last.Mode != ModeByte {
+		t.Errorf("expected the trailing run to use ModeByte, got %d", last.Mode)
+	}
+}
+
+func TestSegmentBodyBitsNumeric(t *testing.T) {
+	cases := []struct {
+		digits int
+		bits   int
+	}{
+		{3, 10},
+		{4, 14},
+		{5, 17},
+		{6, 20},
+	}
+	for _, c := range cases {
+		if got := segmentBodyBits(ModeNumeric, c.digits); got != c.bits {
+			t.Errorf("segmentBodyBits(Numeric, %d) = %d, want %d", c.digits, got, c.bits)
+		}
+	}
+}
+
+func TestNewQRCodeSegmentsBuildsSymbol(t *testing.T) {
+	segments := []Segment{
+		{Mode: ModeAlphanumeric, Data: []byte("HELLO WORLD")},
+	}
+	qr, err := NewQRCodeSegments(segments, LevelM)
+	if err != nil {
+		t.Fatalf("NewQRCodeSegments failed: %v", err)
+	}
+	if qr.Version < 1 {
+		t.Errorf("unexpected version %d", qr.Version)
+	}
+}
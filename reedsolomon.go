@@ -1,5 +1,7 @@
 package qrcode
 
+import "errors"
+
 // Galois Field (256) logic for QR Code Reed-Solomon error correction.
 // Primitive Polynomial: x^8 + x^4 + x^3 + x^2 + 1 (0x11D or 285)
 
@@ -65,6 +67,190 @@ func GenerateGeneratorPoly(numECCodewords int) []int {
 	return gen
 }
 
+// evalPolyHornerHighLow evaluates poly (coefficients ordered highest-degree
+// first, as produced by CalculateECCodewords/splitIntoBlocks) at x.
+func evalPolyHornerHighLow(poly []int, x int) int {
+	result := 0
+	for _, coef := range poly {
+		result = gfMul(result, x) ^ coef
+	}
+	return result
+}
+
+// evalPolyLowHigh evaluates poly (coefficients ordered lowest-degree first,
+// the convention used by the error-locator/evaluator polynomials below) at x.
+func evalPolyLowHigh(poly []int, x int) int {
+	result := 0
+	xPow := 1
+	for _, c := range poly {
+		result ^= gfMul(c, xPow)
+		xPow = gfMul(xPow, x)
+	}
+	return result
+}
+
+// gfPolyMulLowHigh multiplies two low-degree-first polynomials.
+func gfPolyMulLowHigh(p, q []int) []int {
+	res := make([]int, len(p)+len(q)-1)
+	for i, pc := range p {
+		if pc == 0 {
+			continue
+		}
+		for j, qc := range q {
+			res[i+j] ^= gfMul(pc, qc)
+		}
+	}
+	return res
+}
+
+// trimPoly drops trailing (highest-degree) zero coefficients from a
+// low-degree-first polynomial, leaving at least the constant term.
+func trimPoly(p []int) []int {
+	i := len(p) - 1
+	for i > 0 && p[i] == 0 {
+		i--
+	}
+	return p[:i+1]
+}
+
+// berlekampMassey finds the shortest linear feedback shift register (the
+// error locator polynomial, coefficients lowest-degree first with a leading
+// 1 constant term) that generates the given syndrome sequence.
+func berlekampMassey(syndromes []int) []int {
+	c := []int{1} // current error locator candidate
+	b := []int{1} // error locator from before the last length change
+	l := 0
+	m := 1
+	lastDiscrepancy := 1
+
+	for n := 0; n < len(syndromes); n++ {
+		delta := syndromes[n]
+		for i := 1; i <= l; i++ {
+			if i < len(c) {
+				delta ^= gfMul(c[i], syndromes[n-i])
+			}
+		}
+		if delta == 0 {
+			m++
+			continue
+		}
+		t := append([]int{}, c...)
+		coef := gfDiv(delta, lastDiscrepancy)
+		need := m + len(b)
+		if need > len(c) {
+			grown := make([]int, need)
+			copy(grown, c)
+			c = grown
+		}
+		for i, bc := range b {
+			c[m+i] ^= gfMul(coef, bc)
+		}
+		if 2*l <= n {
+			l = n + 1 - l
+			b = t
+			lastDiscrepancy = delta
+			m = 1
+		} else {
+			m++
+		}
+	}
+	return trimPoly(c)
+}
+
+// chienSearch returns the array indices (0-based, within a block of length
+// n) where sigma indicates an error, by testing every candidate position's
+// corresponding field element as a root of sigma.
+func chienSearch(sigma []int, n int) []int {
+	var positions []int
+	for p := 0; p < n; p++ {
+		power := (n - 1 - p) % 255
+		invPower := (255 - power) % 255
+		if evalPolyLowHigh(sigma, expTable[invPower]) == 0 {
+			positions = append(positions, p)
+		}
+	}
+	return positions
+}
+
+// formalDerivative computes the formal derivative of a low-degree-first
+// polynomial over GF(2^8) (characteristic 2, so only odd-degree terms
+// survive, shifted down by one degree).
+func formalDerivative(p []int) []int {
+	if len(p) <= 1 {
+		return nil
+	}
+	deriv := make([]int, len(p)-1)
+	for i := 1; i < len(p); i += 2 {
+		deriv[i-1] = p[i]
+	}
+	return deriv
+}
+
+// CorrectErrors applies Reed-Solomon error correction to one block: codewords
+// holds the full block (data codewords followed by its numEC error
+// correction codewords, highest-degree first, matching CalculateECCodewords'
+// layout). It returns the corrected data codewords with the EC codewords
+// stripped, correcting up to numEC/2 errors.
+func CorrectErrors(codewords []int, numEC int) ([]int, error) {
+	n := len(codewords)
+	k := n - numEC
+
+	syndromes := make([]int, numEC)
+	hasError := false
+	for i := 0; i < numEC; i++ {
+		s := evalPolyHornerHighLow(codewords, expTable[i])
+		syndromes[i] = s
+		if s != 0 {
+			hasError = true
+		}
+	}
+	if !hasError {
+		return append([]int{}, codewords[:k]...), nil
+	}
+
+	sigma := berlekampMassey(syndromes)
+	errCount := len(sigma) - 1
+	if errCount == 0 || errCount*2 > numEC {
+		return nil, errors.New("qrcode: too many errors to correct in block")
+	}
+
+	positions := chienSearch(sigma, n)
+	if len(positions) != errCount {
+		return nil, errors.New("qrcode: error locator has unexpected number of roots")
+	}
+
+	omega := trimPoly(gfPolyMulLowHigh(syndromes, sigma))
+	if len(omega) > numEC {
+		omega = omega[:numEC]
+	}
+	sigmaPrime := formalDerivative(sigma)
+
+	corrected := append([]int{}, codewords...)
+	for _, p := range positions {
+		power := (n - 1 - p) % 255
+		locator := expTable[power] // X_l
+		invX := expTable[(255-power)%255]
+
+		num := evalPolyLowHigh(omega, invX)
+		den := evalPolyLowHigh(sigmaPrime, invX)
+		if den == 0 {
+			return nil, errors.New("qrcode: Forney algorithm division by zero")
+		}
+		// Forney's formula: e_l = X_l * Omega(X_l^-1) / sigma'(X_l^-1).
+		corrected[p] ^= gfMul(locator, gfDiv(num, den))
+	}
+
+	// Verify the correction actually zeroes the syndromes; if not, the
+	// block has more errors than it can reliably correct.
+	for i := 0; i < numEC; i++ {
+		if evalPolyHornerHighLow(corrected, expTable[i]) != 0 {
+			return nil, errors.New("qrcode: error correction failed to validate")
+		}
+	}
+
+	return corrected[:k], nil
+}
+
 // CalculateECCodewords generates error correction codewords for the given data.
 func CalculateECCodewords(data []int, numECCodewords int) []int {
 	generator := GenerateGeneratorPoly(numECCodewords)
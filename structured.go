@@ -0,0 +1,78 @@
+package qrcode
+
+import "errors"
+
+// structuredAppendHeaderBits is the fixed width, in bits, of the Structured
+// Append header placed immediately after the mode indicator: 4 bits of
+// sequence number, 4 bits of (total count - 1), and 8 bits of parity.
+const structuredAppendHeaderBits = 4 + 4 + 8
+
+// NewStructuredAppend splits content across up to 16 QR symbols using
+// ISO/IEC 18004 Structured Append mode, for payloads too large for a single
+// symbol at maxVersion. Every symbol shares the same version and EC level,
+// and carries a Structured Append header (mode indicator 0011, sequence
+// number, total count - 1, and an 8-bit parity byte computed as the XOR of
+// every byte in the full original payload) immediately before its Byte-mode
+// data segment. If content fits in a single symbol at maxVersion or smaller,
+// a one-element slice with no Structured Append header is returned.
+func NewStructuredAppend(content string, level int, maxVersion int) ([]*QRCode, error) {
+	if maxVersion < 1 || maxVersion > 40 {
+		return nil, errors.New("qrcode: maxVersion must be between 1 and 40")
+	}
+	data := []byte(content)
+
+	if qr, err := NewQRCodeWithOptions(content, level, Options{Mask: -1}); err == nil && qr.Version <= maxVersion {
+		return []*QRCode{qr}, nil
+	}
+
+	vInfo := versionTable[maxVersion][level]
+	bodyCapacityBits := (vInfo.TotalCodewords-vInfo.ECCodewords*vInfo.Blocks)*8 -
+		4 - structuredAppendHeaderBits - 4 - countIndicatorBits(ModeByte, maxVersion)
+	maxBytesPerSymbol := bodyCapacityBits / 8
+	if maxBytesPerSymbol <= 0 {
+		return nil, errors.New("qrcode: maxVersion too small to hold a Structured Append header")
+	}
+
+	total := (len(data) + maxBytesPerSymbol - 1) / maxBytesPerSymbol
+	if total < 1 {
+		total = 1
+	}
+	if total > 16 {
+		return nil, errors.New("qrcode: content requires more than 16 symbols at this maxVersion/level")
+	}
+
+	parity := 0
+	for _, b := range data {
+		parity ^= int(b)
+	}
+
+	chunkSize := (len(data) + total - 1) / total
+	symbols := make([]*QRCode, 0, total)
+	for seq := 0; seq < total; seq++ {
+		start := seq * chunkSize
+		if start > len(data) {
+			start = len(data)
+		}
+		end := start + chunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := data[start:end]
+
+		buf := NewBitBuffer()
+		buf.Put(ModeStructuredAppend, 4)
+		buf.Put(seq, 4)
+		buf.Put(total-1, 4)
+		buf.Put(parity, 8)
+
+		buf.Put(ModeByte, 4)
+		buf.Put(len(chunk), countIndicatorBits(ModeByte, maxVersion))
+		if err := encodeSegmentBody(buf, ModeByte, chunk); err != nil {
+			return nil, err
+		}
+
+		finalMessage := finishAndInterleave(buf, vInfo)
+		symbols = append(symbols, buildMatrix(maxVersion, level, finalMessage, Options{Mask: -1}))
+	}
+	return symbols, nil
+}
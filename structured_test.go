@@ -0,0 +1,119 @@
+package qrcode
+
+import "testing"
+
+// decodeSymbolBits reverses mask/interleaving/RS-correction on a symbol built
+// directly by this package (no image involved) and returns its raw data bits,
+// for tests that need to inspect a Structured Append header.
+func decodeSymbolBits(t *testing.T, qr *QRCode) []bool {
+	t.Helper()
+	_, _, isFunction := newFunctionPatterns(qr.Version)
+	unmasked := applyMask(qr.Modules, isFunction, qr.Mask)
+	bits := extractCodewordBits(unmasked, isFunction, qr.Size)
+	codewords := bitsToBytes(bits)
+
+	vInfo := versionTable[qr.Version][qr.Level]
+	dataTotal := vInfo.TotalCodewords - vInfo.ECCodewords*vInfo.Blocks
+	blocks := deinterleaveBlocks(codewords, vInfo.Blocks, vInfo.ECCodewords, dataTotal)
+
+	var dataCodewords []int
+	for _, blk := range blocks {
+		full := append(append([]int{}, blk.Data...), blk.EC...)
+		corrected, err := CorrectErrors(full, vInfo.ECCodewords)
+		if err != nil {
+			t.Fatalf("CorrectErrors failed: %v", err)
+		}
+		dataCodewords = append(dataCodewords, corrected...)
+	}
+
+	dataBits := make([]bool, 0, len(dataCodewords)*8)
+	for _, cw := range dataCodewords {
+		for i := 7; i >= 0; i-- {
+			dataBits = append(dataBits, (cw>>i)&1 == 1)
+		}
+	}
+	return dataBits
+}
+
+func TestNewStructuredAppendFitsSingleSymbol(t *testing.T) {
+	symbols, err := NewStructuredAppend("Hello World", LevelL, 10)
+	if err != nil {
+		t.Fatalf("NewStructuredAppend failed: %v", err)
+	}
+	if len(symbols) != 1 {
+		t.Fatalf("expected 1 symbol for short content, got %d", len(symbols))
+	}
+}
+
+func TestNewStructuredAppendSplitsAcrossSymbols(t *testing.T) {
+	content := ""
+	for i := 0; i < 80; i++ {
+		content += "abcdefghij"
+	}
+
+	symbols, err := NewStructuredAppend(content, LevelL, 5)
+	if err != nil {
+		t.Fatalf("NewStructuredAppend failed: %v", err)
+	}
+	if len(symbols) < 2 {
+		t.Fatalf("expected multiple symbols, got %d", len(symbols))
+	}
+
+	var reassembled []byte
+	var parity int
+	for i, qr := range symbols {
+		if qr.Version != 5 {
+			t.Errorf("symbol %d: expected version 5, got %d", i, qr.Version)
+		}
+		if qr.Level != LevelL {
+			t.Errorf("symbol %d: expected LevelL, got %d", i, qr.Level)
+		}
+
+		r := &bitReader{bits: decodeSymbolBits(t, qr)}
+		mode := r.read(4)
+		if mode != ModeStructuredAppend {
+			t.Fatalf("symbol %d: expected Structured Append mode indicator, got %d", i, mode)
+		}
+		seq := r.read(4)
+		total := r.read(4) + 1
+		p := r.read(8)
+		if seq != i {
+			t.Errorf("symbol %d: expected sequence %d, got %d", i, i, seq)
+		}
+		if total != len(symbols) {
+			t.Errorf("symbol %d: expected total %d, got %d", i, len(symbols), total)
+		}
+		parity = p
+
+		byteMode := r.read(4)
+		if byteMode != ModeByte {
+			t.Fatalf("symbol %d: expected ModeByte segment, got %d", i, byteMode)
+		}
+		count := r.read(countIndicatorBits(ModeByte, qr.Version))
+		for j := 0; j < count; j++ {
+			reassembled = append(reassembled, byte(r.read(8)))
+		}
+	}
+
+	if string(reassembled) != content {
+		t.Errorf("reassembled content mismatch: got %d bytes, want %d", len(reassembled), len(content))
+	}
+
+	want := 0
+	for _, b := range []byte(content) {
+		want ^= int(b)
+	}
+	if parity != want {
+		t.Errorf("expected parity %d, got %d", want, parity)
+	}
+}
+
+func TestNewStructuredAppendRejectsTooLarge(t *testing.T) {
+	content := ""
+	for i := 0; i < 5000; i++ {
+		content += "x"
+	}
+	if _, err := NewStructuredAppend(content, LevelH, 1); err == nil {
+		t.Fatalf("expected an error for content exceeding 16 symbols at V1-H")
+	}
+}
@@ -0,0 +1,114 @@
+package qrcode
+
+import (
+	"fmt"
+	"io"
+)
+
+// SVGOptions controls the rendering of WriteSVG.
+type SVGOptions struct {
+	// ModuleSize is the side length, in SVG user units, of one module.
+	// Defaults to 10 when zero.
+	ModuleSize int
+	// QuietZone is the number of light modules of padding drawn around the
+	// symbol. Defaults to 4 (the spec minimum) when zero.
+	QuietZone int
+	// Foreground and Background are SVG color values for dark and light
+	// modules respectively (e.g. "#000000", "none" for transparency).
+	// Default to "#000000" and "#ffffff" when empty.
+	Foreground string
+	Background string
+	// Logo, if non-empty, is embedded as an SVG <image> (e.g. a data URI)
+	// centered over the symbol, with a LogoSize x LogoSize white knock-out
+	// square behind it. Pair this with LevelH so the covered modules stay
+	// within the recoverable error budget.
+	Logo     string
+	LogoSize int
+}
+
+// WriteSVG writes the QR code to w as a compact SVG document: each
+// horizontal run of dark modules in a row is emitted as one rectangle of a
+// single <path>, rather than one rectangle per module, so the file stays
+// small even at high versions.
+func (qr *QRCode) WriteSVG(w io.Writer, opts SVGOptions) error {
+	moduleSize := opts.ModuleSize
+	if moduleSize == 0 {
+		moduleSize = 10
+	}
+	border := opts.QuietZone
+	if border == 0 {
+		border = 4
+	}
+	fg := opts.Foreground
+	if fg == "" {
+		fg = "#000000"
+	}
+	bg := opts.Background
+	if bg == "" {
+		bg = "#ffffff"
+	}
+
+	dim := (qr.Size + 2*border) * moduleSize
+
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n", dim, dim); err != nil {
+		return err
+	}
+
+	if bg != "none" {
+		if _, err := fmt.Fprintf(w, `<rect width="%d" height="%d" fill="%s"/>`+"\n", dim, dim, bg); err != nil {
+			return err
+		}
+	}
+
+	if fg != "none" {
+		path := modulesPath(qr.Modules, qr.Size, border, moduleSize)
+		if path != "" {
+			if _, err := fmt.Fprintf(w, `<path d="%s" fill="%s"/>`+"\n", path, fg); err != nil {
+				return err
+			}
+		}
+	}
+
+	if opts.Logo != "" {
+		logoSize := opts.LogoSize
+		if logoSize == 0 {
+			logoSize = dim / 4
+		}
+		x := (dim - logoSize) / 2
+		if _, err := fmt.Fprintf(w, `<rect x="%d" y="%d" width="%d" height="%d" fill="#ffffff"/>`+"\n", x, x, logoSize, logoSize); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, `<image x="%d" y="%d" width="%d" height="%d" href="%s"/>`+"\n", x, x, logoSize, logoSize, opts.Logo); err != nil {
+			return err
+		}
+	}
+
+	_, err := io.WriteString(w, "</svg>\n")
+	return err
+}
+
+// modulesPath builds a single SVG path string for every dark module, merging
+// horizontally adjacent dark modules in each row into one rectangle segment
+// (`M x y h<run>v1h-<run>z`) to keep large symbols compact.
+func modulesPath(modules [][]bool, size, border, moduleSize int) string {
+	var path []byte
+	for r := 0; r < size; r++ {
+		c := 0
+		for c < size {
+			if !modules[r][c] {
+				c++
+				continue
+			}
+			run := 0
+			for c+run < size && modules[r][c+run] {
+				run++
+			}
+			x := (c + border) * moduleSize
+			y := (r + border) * moduleSize
+			w := run * moduleSize
+			path = append(path, fmt.Sprintf(`M%d %dh%dv%dh-%dz`, x, y, w, moduleSize, w)...)
+			c += run
+		}
+	}
+	return string(path)
+}
@@ -0,0 +1,46 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteSVGWellFormed(t *testing.T) {
+	qr, err := NewQRCode("Hello World", LevelL)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := qr.WriteSVG(&buf, SVGOptions{}); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "<svg") {
+		t.Errorf("expected output to start with <svg, got %q", out[:minInt(20, len(out))])
+	}
+	if !strings.HasSuffix(strings.TrimRight(out, "\n"), "</svg>") {
+		t.Errorf("expected output to end with </svg>")
+	}
+	if strings.Count(out, "<path") != 1 {
+		t.Errorf("expected exactly one merged <path>, got %d", strings.Count(out, "<path"))
+	}
+}
+
+func TestWriteSVGTransparentBackground(t *testing.T) {
+	qr, err := NewQRCode("Hi", LevelL)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := qr.WriteSVG(&buf, SVGOptions{Background: "none"}); err != nil {
+		t.Fatalf("WriteSVG failed: %v", err)
+	}
+
+	if strings.Contains(buf.String(), "<rect width=") {
+		t.Errorf("expected no background rect when Background is \"none\"")
+	}
+}
@@ -0,0 +1,83 @@
+package qrcode
+
+import "io"
+
+// TerminalOptions controls the rendering of WriteTerminal.
+type TerminalOptions struct {
+	// QuietZone is the number of light modules of padding drawn around the
+	// symbol. Defaults to 4 (the spec minimum) when zero.
+	QuietZone int
+	// Invert swaps the dark/light glyphs, for light-themed terminals that
+	// expect white-on-black output instead of black-on-white.
+	Invert bool
+	// ASCII renders with plain "##"/"  " character pairs instead of the
+	// half-block Unicode glyphs, for terminals or fonts without block
+	// element support.
+	ASCII bool
+}
+
+// WriteTerminal renders the QR code to w as text for display in a terminal.
+// By default it pairs two module rows per printed line using the Unicode
+// half-block characters (▀, ▄, █, space), so the output keeps a roughly
+// square aspect ratio in typical monospace fonts.
+func (qr *QRCode) WriteTerminal(w io.Writer, opts TerminalOptions) error {
+	border := opts.QuietZone
+	if border == 0 {
+		border = 4
+	}
+
+	dim := qr.Size + 2*border
+	dark := func(r, c int) bool {
+		if r < border || c < border || r >= dim-border || c >= dim-border {
+			return false
+		}
+		return qr.Modules[r-border][c-border]
+	}
+	if opts.Invert {
+		orig := dark
+		dark = func(r, c int) bool { return !orig(r, c) }
+	}
+
+	if opts.ASCII {
+		for r := 0; r < dim; r++ {
+			for c := 0; c < dim; c++ {
+				if dark(r, c) {
+					if _, err := io.WriteString(w, "##"); err != nil {
+						return err
+					}
+				} else {
+					if _, err := io.WriteString(w, "  "); err != nil {
+						return err
+					}
+				}
+			}
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for r := 0; r < dim; r += 2 {
+		for c := 0; c < dim; c++ {
+			top := dark(r, c)
+			bottom := r+1 < dim && dark(r+1, c)
+			glyph := " "
+			switch {
+			case top && bottom:
+				glyph = "█"
+			case top:
+				glyph = "▀"
+			case bottom:
+				glyph = "▄"
+			}
+			if _, err := io.WriteString(w, glyph); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
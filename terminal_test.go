@@ -0,0 +1,57 @@
+package qrcode
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteTerminalDimensions(t *testing.T) {
+	qr, err := NewQRCode("Hello World", LevelL)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := qr.WriteTerminal(&buf, TerminalOptions{}); err != nil {
+		t.Fatalf("WriteTerminal failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	dim := qr.Size + 2*4
+	wantLines := (dim + 1) / 2
+	if len(lines) != wantLines {
+		t.Fatalf("expected %d lines, got %d", wantLines, len(lines))
+	}
+	for _, line := range lines {
+		if got := len([]rune(line)); got != dim {
+			t.Errorf("expected %d runes per line, got %d", dim, got)
+		}
+	}
+}
+
+func TestWriteTerminalASCIIFallback(t *testing.T) {
+	qr, err := NewQRCode("Hi", LevelL)
+	if err != nil {
+		t.Fatalf("Failed to create QR: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := qr.WriteTerminal(&buf, TerminalOptions{ASCII: true, QuietZone: 2}); err != nil {
+		t.Fatalf("WriteTerminal failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	dim := qr.Size + 2*2
+	if len(lines) != dim {
+		t.Fatalf("expected %d lines, got %d", dim, len(lines))
+	}
+	for _, line := range lines {
+		if len(line) != dim*2 {
+			t.Errorf("expected %d chars per line, got %d", dim*2, len(line))
+		}
+		if strings.ContainsAny(line, "▀▄█") {
+			t.Errorf("ASCII fallback should not emit block glyphs, got %q", line)
+		}
+	}
+}
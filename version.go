@@ -0,0 +1,255 @@
+package qrcode
+
+// Version capacity tables (ISO/IEC 18004 Annex D / Table 9), indexed by
+// version (1-40, index 0 unused) and then by EC level in the fixed order
+// L, M, Q, H.
+
+// totalCodewordsByVersion is the total number of codewords (data + EC) a
+// symbol of the given version can hold, independent of EC level.
+var totalCodewordsByVersion = [41]int{
+	0,
+	26, 44, 70, 100, 134, 172, 196, 242, 292, 346,
+	404, 466, 532, 581, 655, 733, 815, 901, 991, 1085,
+	1156, 1258, 1364, 1474, 1588, 1706, 1828, 1921, 2051, 2185,
+	2323, 2465, 2611, 2761, 2876, 3034, 3196, 3362, 3532, 3706,
+}
+
+// eccCodewordsPerBlock gives the number of EC codewords in each block, for
+// EC levels [L, M, Q, H].
+var eccCodewordsPerBlock = [41][4]int{
+	{0, 0, 0, 0},
+	{7, 10, 13, 17}, {10, 16, 22, 28}, {15, 26, 18, 22}, {20, 18, 26, 16}, {26, 24, 18, 22},
+	{18, 16, 24, 28}, {20, 18, 18, 26}, {24, 22, 22, 26}, {30, 22, 20, 24}, {18, 26, 24, 28},
+	{20, 30, 28, 24}, {24, 22, 26, 28}, {26, 22, 24, 22}, {30, 24, 20, 24}, {22, 24, 30, 24},
+	{24, 28, 24, 30}, {28, 28, 28, 28}, {30, 26, 28, 28}, {28, 26, 26, 26}, {28, 26, 30, 28},
+	{28, 26, 28, 30}, {28, 28, 30, 24}, {30, 28, 30, 30}, {30, 28, 30, 30}, {26, 28, 30, 30},
+	{28, 28, 28, 30}, {30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30},
+	{30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30},
+	{30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30}, {30, 28, 30, 30},
+}
+
+// numBlocksPerVersion gives the total number of blocks (both groups
+// combined) the data codewords are split into, for EC levels [L, M, Q, H].
+var numBlocksPerVersion = [41][4]int{
+	{0, 0, 0, 0},
+	{1, 1, 1, 1}, {1, 1, 1, 1}, {1, 1, 2, 2}, {1, 2, 2, 4}, {1, 2, 4, 4},
+	{2, 4, 4, 4}, {2, 4, 6, 5}, {2, 4, 6, 6}, {2, 5, 8, 8}, {4, 5, 8, 8},
+	{4, 5, 8, 11}, {4, 8, 10, 11}, {4, 9, 12, 16}, {4, 9, 16, 16}, {6, 10, 12, 18},
+	{6, 10, 17, 16}, {6, 11, 16, 19}, {6, 13, 18, 21}, {7, 14, 21, 25}, {8, 16, 20, 25},
+	{8, 17, 23, 25}, {9, 17, 23, 34}, {9, 18, 25, 30}, {10, 20, 27, 32}, {12, 21, 29, 35},
+	{12, 23, 34, 37}, {12, 25, 34, 40}, {13, 26, 35, 42}, {14, 28, 38, 45}, {15, 29, 40, 48},
+	{16, 31, 43, 51}, {17, 33, 45, 54}, {18, 35, 48, 57}, {19, 37, 51, 60}, {19, 38, 53, 63},
+	{20, 40, 56, 66}, {21, 43, 59, 70}, {22, 45, 62, 74}, {24, 47, 65, 77}, {25, 49, 68, 81},
+}
+
+// alignmentPatternPositions gives the row/column coordinates (the same set
+// is used for both axes) at which alignment pattern centers may be placed,
+// per ISO/IEC 18004 Table E.1. Version 1 has no alignment patterns.
+var alignmentPatternPositions = map[int][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50},
+	11: {6, 30, 54}, 12: {6, 32, 58}, 13: {6, 34, 62},
+	14: {6, 26, 46, 66}, 15: {6, 26, 48, 70}, 16: {6, 26, 50, 74},
+	17: {6, 30, 54, 78}, 18: {6, 30, 56, 82}, 19: {6, 30, 58, 86}, 20: {6, 34, 62, 90},
+	21: {6, 28, 50, 72, 94}, 22: {6, 26, 50, 74, 98}, 23: {6, 30, 54, 78, 102},
+	24: {6, 28, 54, 80, 106}, 25: {6, 32, 58, 84, 110}, 26: {6, 30, 58, 86, 114},
+	27: {6, 34, 62, 90, 118},
+	28: {6, 26, 50, 74, 98, 122}, 29: {6, 30, 54, 78, 102, 126}, 30: {6, 26, 52, 78, 104, 130},
+	31: {6, 30, 56, 82, 108, 134}, 32: {6, 34, 60, 86, 112, 138}, 33: {6, 30, 58, 86, 114, 142},
+	34: {6, 34, 62, 90, 118, 146},
+	35: {6, 30, 54, 78, 102, 126, 150}, 36: {6, 24, 50, 76, 102, 128, 154},
+	37: {6, 28, 54, 80, 106, 132, 158}, 38: {6, 32, 58, 84, 110, 136, 162},
+	39: {6, 26, 54, 82, 110, 138, 166}, 40: {6, 30, 58, 86, 114, 142, 170},
+}
+
+// levelIndex maps an ECC Level constant to its index (L, M, Q, H order)
+// in the per-version tables above.
+func levelIndex(level int) int {
+	switch level {
+	case LevelL:
+		return 0
+	case LevelM:
+		return 1
+	case LevelQ:
+		return 2
+	case LevelH:
+		return 3
+	}
+	return 0
+}
+
+// versionTable is built at init time from the raw per-version arrays above.
+var versionTable map[int]map[int]VersionInfo
+
+func init() {
+	versionTable = make(map[int]map[int]VersionInfo, 40)
+	levels := []int{LevelL, LevelM, LevelQ, LevelH}
+	for v := 1; v <= 40; v++ {
+		versionTable[v] = make(map[int]VersionInfo, 4)
+		for _, level := range levels {
+			idx := levelIndex(level)
+			versionTable[v][level] = VersionInfo{
+				TotalCodewords: totalCodewordsByVersion[v],
+				ECCodewords:    eccCodewordsPerBlock[v][idx],
+				Blocks:         numBlocksPerVersion[v][idx],
+			}
+		}
+	}
+}
+
+// countIndicatorBits returns the width, in bits, of the character-count
+// indicator for the given mode at the given version, per ISO/IEC 18004
+// Table 3. The width switches on the version group: 1-9, 10-26, 27-40.
+func countIndicatorBits(mode, version int) int {
+	switch mode {
+	case ModeNumeric:
+		switch {
+		case version <= 9:
+			return 10
+		case version <= 26:
+			return 12
+		default:
+			return 14
+		}
+	case ModeAlphanumeric:
+		switch {
+		case version <= 9:
+			return 9
+		case version <= 26:
+			return 11
+		default:
+			return 13
+		}
+	case ModeKanji:
+		switch {
+		case version <= 9:
+			return 8
+		case version <= 26:
+			return 10
+		default:
+			return 12
+		}
+	default: // ModeByte
+		switch {
+		case version <= 9:
+			return 8
+		default:
+			return 16
+		}
+	}
+}
+
+// codewordBlock is one block of a version's data, holding its own data and
+// error-correction codewords.
+type codewordBlock struct {
+	Data []int
+	EC   []int
+}
+
+// splitIntoBlocks divides data into numBlocks blocks per ISO/IEC 18004
+// Annex D: some blocks get one extra data codeword ("long" blocks), which
+// always come after the "short" blocks. Each block's EC codewords are
+// computed independently.
+func splitIntoBlocks(data []int, numBlocks, ecPerBlock int) []codewordBlock {
+	shortLen := len(data) / numBlocks
+	numLongBlocks := len(data) % numBlocks
+	numShortBlocks := numBlocks - numLongBlocks
+
+	blocks := make([]codewordBlock, numBlocks)
+	pos := 0
+	for i := 0; i < numBlocks; i++ {
+		length := shortLen
+		if i >= numShortBlocks {
+			length = shortLen + 1
+		}
+		blockData := append([]int{}, data[pos:pos+length]...)
+		pos += length
+		blocks[i] = codewordBlock{
+			Data: blockData,
+			EC:   CalculateECCodewords(blockData, ecPerBlock),
+		}
+	}
+	return blocks
+}
+
+// interleaveBlocks writes data codewords column-by-column across blocks
+// (short blocks simply run out of columns sooner), followed by EC
+// codewords column-by-column across all blocks, per ISO/IEC 18004 Annex D.
+func interleaveBlocks(blocks []codewordBlock) []int {
+	maxDataLen := 0
+	for _, b := range blocks {
+		if len(b.Data) > maxDataLen {
+			maxDataLen = len(b.Data)
+		}
+	}
+
+	out := make([]int, 0, len(blocks)*maxDataLen)
+	for col := 0; col < maxDataLen; col++ {
+		for _, b := range blocks {
+			if col < len(b.Data) {
+				out = append(out, b.Data[col])
+			}
+		}
+	}
+
+	ecLen := len(blocks[0].EC)
+	for col := 0; col < ecLen; col++ {
+		for _, b := range blocks {
+			out = append(out, b.EC[col])
+		}
+	}
+	return out
+}
+
+// deinterleaveBlocks is the inverse of interleaveBlocks/splitIntoBlocks: it
+// splits a flat sequence of codewords (in interleaved column-by-column
+// order) back into per-block data and EC codewords, given the block layout
+// implied by a VersionInfo (dataTotal is the total data codeword count,
+// TotalCodewords-ECCodewords*Blocks).
+func deinterleaveBlocks(codewords []int, numBlocks, ecPerBlock, dataTotal int) []codewordBlock {
+	shortLen := dataTotal / numBlocks
+	numLongBlocks := dataTotal % numBlocks
+	numShortBlocks := numBlocks - numLongBlocks
+
+	blocks := make([]codewordBlock, numBlocks)
+	maxDataLen := shortLen
+	if numLongBlocks > 0 {
+		maxDataLen = shortLen + 1
+	}
+	for i := range blocks {
+		length := shortLen
+		if i >= numShortBlocks {
+			length = shortLen + 1
+		}
+		blocks[i].Data = make([]int, length)
+		blocks[i].EC = make([]int, ecPerBlock)
+	}
+
+	idx := 0
+	for col := 0; col < maxDataLen; col++ {
+		for i := range blocks {
+			if col < len(blocks[i].Data) {
+				blocks[i].Data[col] = codewords[idx]
+				idx++
+			}
+		}
+	}
+	for col := 0; col < ecPerBlock; col++ {
+		for i := range blocks {
+			blocks[i].EC[col] = codewords[idx]
+			idx++
+		}
+	}
+	return blocks
+}
+
+// computeVersionInfoBits computes the 18-bit version information value
+// (6 bits of version number followed by a 12-bit BCH error-correction code)
+// used by symbols of version 7 and above.
+func computeVersionInfoBits(version int) int {
+	rem := version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	return version<<12 | rem
+}
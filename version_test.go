@@ -0,0 +1,114 @@
+package qrcode
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSplitAndInterleaveBlocks(t *testing.T) {
+	// Version 5, Level Q: 2 blocks of 15 data codewords + 2 blocks of 16.
+	data := make([]int, 62)
+	for i := range data {
+		data[i] = i
+	}
+
+	blocks := splitIntoBlocks(data, 4, 18)
+	if len(blocks) != 4 {
+		t.Fatalf("expected 4 blocks, got %d", len(blocks))
+	}
+	for i, b := range blocks {
+		wantLen := 15
+		if i >= 2 {
+			wantLen = 16
+		}
+		if len(b.Data) != wantLen {
+			t.Errorf("block %d: expected %d data codewords, got %d", i, wantLen, len(b.Data))
+		}
+		if len(b.EC) != 18 {
+			t.Errorf("block %d: expected 18 EC codewords, got %d", i, len(b.EC))
+		}
+	}
+
+	interleaved := interleaveBlocks(blocks)
+	// 2*15 + 2*16 data codewords, then 4*18 EC codewords.
+	wantTotal := 2*15 + 2*16 + 4*18
+	if len(interleaved) != wantTotal {
+		t.Fatalf("expected %d interleaved codewords, got %d", wantTotal, len(interleaved))
+	}
+	// First column: block0[0], block1[0], block2[0], block3[0].
+	if interleaved[0] != blocks[0].Data[0] || interleaved[1] != blocks[1].Data[0] ||
+		interleaved[2] != blocks[2].Data[0] || interleaved[3] != blocks[3].Data[0] {
+		t.Errorf("unexpected interleave order at start: %v", interleaved[:4])
+	}
+}
+
+// roundTripSize creates a QR code for a size-byte payload, checks its
+// reported dimensions, and verifies it survives a PNG encode/decode
+// round-trip.
+func roundTripSize(t *testing.T, size int) *QRCode {
+	t.Helper()
+
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte('a' + i%26)
+	}
+
+	qr, err := NewQRCode(string(content), LevelM)
+	if err != nil {
+		t.Fatalf("size %d: failed to create QR: %v", size, err)
+	}
+	if qr.Version < 1 || qr.Version > 40 {
+		t.Errorf("size %d: unexpected version %d", size, qr.Version)
+	}
+	wantSize := 21 + 4*(qr.Version-1)
+	if qr.Size != wantSize {
+		t.Errorf("size %d: expected matrix dimension %d, got %d", size, wantSize, qr.Size)
+	}
+
+	var buf bytes.Buffer
+	if err := qr.WritePNG(&buf, 10); err != nil {
+		t.Fatalf("size %d: failed to write PNG: %v", size, err)
+	}
+	decoded, err := Decode(&buf)
+	if err != nil {
+		t.Fatalf("size %d (version %d): failed to decode: %v", size, qr.Version, err)
+	}
+	if decoded != string(content) {
+		t.Errorf("size %d (version %d): round-trip mismatch, got %d bytes back", size, qr.Version, len(decoded))
+	}
+	return qr
+}
+
+func TestNewQRCodeAcrossVersions(t *testing.T) {
+	// Grow the payload one byte at a time through V1-V10, so every low
+	// version is exercised at its minimum size instead of being skipped
+	// over. V2-V7 in particular carry alignment patterns that the image
+	// decoder must locate correctly; a handful of hand-picked sizes landing
+	// only on V1 and high versions let regressions there ship undetected.
+	size := 0
+	for version := 1; version <= 10; version++ {
+		var qr *QRCode
+		for {
+			size++
+			var err error
+			qr, err = NewQRCode(string(make([]byte, size)), LevelM)
+			if err != nil {
+				t.Fatalf("size %d: failed to create QR: %v", size, err)
+			}
+			if qr.Version >= version {
+				break
+			}
+		}
+		if qr.Version != version {
+			t.Fatalf("version %d unreachable: size %d already produced version %d", version, size, qr.Version)
+		}
+		roundTripSize(t, size)
+	}
+
+	// Spot-check medium and large versions too. 2300 bytes stays under the
+	// V40-M byte capacity (2331 bytes); a larger size would make NewQRCode
+	// correctly return a capacity error.
+	for _, size := range []int{500, 1500, 2300} {
+		roundTripSize(t, size)
+	}
+}